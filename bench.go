@@ -0,0 +1,126 @@
+package metis
+
+import (
+	"sort"
+	"time"
+)
+
+// BenchConfig is one setting compared by BenchmarkPartition
+type BenchConfig struct {
+	Name    string  // Label for reports
+	Nparts  int32   // Number of partitions
+	Options []int32 // METIS options, nil for the defaults; OptionPType selects the method
+	Repeats int     // Timed runs, of which the fastest is reported; at least 1
+}
+
+// BenchOutcome is the result of one BenchConfig. Cut, volume and imbalance
+// come from the last run, which with a fixed seed is the same as every run.
+type BenchOutcome struct {
+	Config     BenchConfig
+	EdgeCut    int32         // Total weight of the cut edges
+	CommVolume int32         // Communication volume, as in Metrics
+	Imbalance  float64       // Heaviest partition weight over the average
+	Time       time.Duration // Fastest of the Repeats runs
+	Err        error         // Non-nil if partitioning failed; the metrics are then zero
+}
+
+// BenchmarkPartition partitions g once per config, as PartitionGraph would,
+// and measures the quality and speed of each result so options can be tuned
+// empirically on the caller's own graphs and hardware. Configs run
+// sequentially in the order given, and the outcomes are returned in the same
+// order; SortBenchOutcomes ranks them.
+func BenchmarkPartition(g *Graph, configs []BenchConfig) []BenchOutcome {
+	outcomes := make([]BenchOutcome, len(configs))
+	for i, cfg := range configs {
+		outcomes[i], _ = benchmarkConfig(g, cfg)
+	}
+	return outcomes
+}
+
+// benchmarkConfig runs a single config, also returning the partition of the
+// last run
+func benchmarkConfig(g *Graph, cfg BenchConfig) (BenchOutcome, []int32) {
+	out := BenchOutcome{Config: cfg}
+	repeats := cfg.Repeats
+	if repeats < 1 {
+		repeats = 1
+	}
+
+	var part []int32
+	for r := 0; r < repeats; r++ {
+		start := time.Now()
+		p, _, err := PartitionGraph(g, cfg.Nparts, cfg.Options)
+		elapsed := time.Since(start)
+		if err != nil {
+			return BenchOutcome{Config: cfg, Err: err}, nil
+		}
+		if r == 0 || elapsed < out.Time {
+			out.Time = elapsed
+		}
+		part = p
+	}
+
+	m := Metrics(g, part, cfg.Nparts)
+	out.EdgeCut = m.EdgeCut
+	out.CommVolume = m.CommVolume
+	if _, max, avg := CalculatePartitionBalance(part, g.Vwgt, cfg.Nparts); avg > 0 {
+		out.Imbalance = max / avg
+	}
+	return out, part
+}
+
+// SortBenchOutcomes orders outcomes from best to worst: successful runs
+// before failed ones, then by increasing edge cut, imbalance and time. The
+// sort is stable, so configs that tie keep their original order.
+func SortBenchOutcomes(outcomes []BenchOutcome) {
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		a, b := outcomes[i], outcomes[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.EdgeCut != b.EdgeCut {
+			return a.EdgeCut < b.EdgeCut
+		}
+		if a.Imbalance != b.Imbalance {
+			return a.Imbalance < b.Imbalance
+		}
+		return a.Time < b.Time
+	})
+}
+
+// MethodComparison is the result of CompareMethods
+type MethodComparison struct {
+	Recursive BenchOutcome // Recursive bisection
+	Kway      BenchOutcome // Multilevel k-way
+	// Similarity is the PartitionSimilarity of the two partitions, or 0 if
+	// either method failed
+	Similarity float64
+}
+
+// CompareMethods partitions g into nparts with both recursive bisection and
+// k-way, one timed run each, and reports the cut, balance and time of each
+// together with how closely the two partitions agree. The methods often
+// disagree even for two partitions, so this helps choose one on the caller's
+// own graph. OptionPType in options is overridden; the other options apply to
+// both runs, and nil selects the defaults.
+func CompareMethods(g *Graph, nparts int32, options []int32) MethodComparison {
+	run := func(name string, ptype PType) (BenchOutcome, []int32) {
+		opts := make([]int32, NoOptions)
+		if options != nil && len(options) == NoOptions {
+			copy(opts, options)
+		} else if err := SetDefaultOptions(opts); err != nil {
+			return BenchOutcome{Config: BenchConfig{Name: name, Nparts: nparts}, Err: err}, nil
+		}
+		opts[OptionPType] = int32(ptype)
+		return benchmarkConfig(g, BenchConfig{Name: name, Nparts: nparts, Options: opts, Repeats: 1})
+	}
+
+	var c MethodComparison
+	var rbPart, kwayPart []int32
+	c.Recursive, rbPart = run("rb", MethodRB)
+	c.Kway, kwayPart = run("kway", MethodKway)
+	if c.Recursive.Err == nil && c.Kway.Err == nil {
+		c.Similarity = PartitionSimilarity(rbPart, kwayPart, nparts)
+	}
+	return c
+}
@@ -0,0 +1,70 @@
+package metis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkPartition(t *testing.T) {
+	g := GenerateGrid2D(12, 12, false)
+
+	rb := make([]int32, NoOptions)
+	SetDefaultOptions(rb)
+	rb[OptionPType] = PTypeRB
+
+	outcomes := BenchmarkPartition(g, []BenchConfig{
+		{Name: "kway", Nparts: 4, Repeats: 3},
+		{Name: "rb", Nparts: 4, Options: rb},
+		{Name: "invalid", Nparts: 0},
+	})
+	require.Len(t, outcomes, 3)
+
+	for _, o := range outcomes[:2] {
+		require.NoError(t, o.Err, o.Config.Name)
+		assert.Positive(t, o.EdgeCut)
+		assert.Positive(t, o.CommVolume)
+		assert.GreaterOrEqual(t, o.Imbalance, 1.0)
+		assert.Positive(t, o.Time)
+	}
+	assert.Error(t, outcomes[2].Err)
+	assert.Equal(t, "invalid", outcomes[2].Config.Name)
+}
+
+func TestSortBenchOutcomes(t *testing.T) {
+	outcomes := []BenchOutcome{
+		{Config: BenchConfig{Name: "failed"}, Err: errors.New("boom")},
+		{Config: BenchConfig{Name: "slow"}, EdgeCut: 10, Imbalance: 1.01, Time: 5},
+		{Config: BenchConfig{Name: "worse"}, EdgeCut: 12, Imbalance: 1.0, Time: 1},
+		{Config: BenchConfig{Name: "fast"}, EdgeCut: 10, Imbalance: 1.01, Time: 2},
+		{Config: BenchConfig{Name: "balanced"}, EdgeCut: 10, Imbalance: 1.0, Time: 9},
+	}
+	SortBenchOutcomes(outcomes)
+
+	var names []string
+	for _, o := range outcomes {
+		names = append(names, o.Config.Name)
+	}
+	assert.Equal(t, []string{"balanced", "fast", "slow", "worse", "failed"}, names)
+}
+
+func TestCompareMethods(t *testing.T) {
+	g := GenerateGrid2D(12, 12, false)
+
+	c := CompareMethods(g, 2, nil)
+	require.NoError(t, c.Recursive.Err)
+	require.NoError(t, c.Kway.Err)
+	assert.Equal(t, "rb", c.Recursive.Config.Name)
+	assert.Equal(t, int32(PTypeRB), c.Recursive.Config.Options[OptionPType])
+	assert.Equal(t, int32(PTypeKway), c.Kway.Config.Options[OptionPType])
+	assert.Positive(t, c.Recursive.EdgeCut)
+	assert.Positive(t, c.Kway.EdgeCut)
+	assert.GreaterOrEqual(t, c.Similarity, 0.5)
+	assert.LessOrEqual(t, c.Similarity, 1.0)
+
+	c = CompareMethods(g, 0, nil)
+	assert.Error(t, c.Recursive.Err)
+	assert.Zero(t, c.Similarity)
+}
@@ -0,0 +1,148 @@
+package metis
+
+// Contract collapses g according to cmap, which maps each vertex to one of
+// ncoarse coarse vertices. Coarse vertex weights are the sums of their fine
+// vertex weights, parallel edges are merged by summing their weights and edges
+// internal to a coarse vertex are dropped. Unweighted graphs are treated as
+// having unit weights, and the coarse graph always carries Vwgt and Adjwgt.
+func (g *Graph) Contract(cmap []int32, ncoarse int32) *Graph {
+	nvtxs := g.NumVertices()
+
+	// Group fine vertices by coarse vertex
+	members := make([][]int32, ncoarse)
+	coarse := &Graph{
+		Xadj:   make([]int32, ncoarse+1),
+		Adjncy: []int32{},
+		Vwgt:   make([]int32, ncoarse),
+		Adjwgt: []int32{},
+	}
+	for v := 0; v < nvtxs; v++ {
+		c := cmap[v]
+		members[c] = append(members[c], int32(v))
+		if g.Vwgt != nil {
+			coarse.Vwgt[c] += g.Vwgt[v]
+		} else {
+			coarse.Vwgt[c]++
+		}
+	}
+
+	// slot[c] is the position of coarse neighbor c in the current list (+1)
+	slot := make([]int32, ncoarse)
+	for c := int32(0); c < ncoarse; c++ {
+		start := int32(len(coarse.Adjncy))
+		for _, v := range members[c] {
+			for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+				u := cmap[g.Adjncy[j]]
+				if u == c {
+					continue
+				}
+				w := int32(1)
+				if g.Adjwgt != nil {
+					w = g.Adjwgt[j]
+				}
+				if slot[u] > start {
+					coarse.Adjwgt[slot[u]-1] += w
+				} else {
+					coarse.Adjncy = append(coarse.Adjncy, u)
+					coarse.Adjwgt = append(coarse.Adjwgt, w)
+					slot[u] = int32(len(coarse.Adjncy))
+				}
+			}
+		}
+		coarse.Xadj[c+1] = int32(len(coarse.Adjncy))
+	}
+
+	return coarse
+}
+
+// CoarsenHEM performs one level of heavy-edge matching coarsening. Each
+// vertex is matched with the unmatched neighbor joined by its heaviest edge,
+// as in METIS's SHEM scheme, and matched pairs are contracted into a single
+// coarse vertex. It returns the coarse graph and cmap, where cmap[v] is the
+// coarse vertex that fine vertex v was merged into.
+func (g *Graph) CoarsenHEM() (coarse *Graph, cmap []int32) {
+	match := g.HeavyEdgeMatching()
+
+	nvtxs := g.NumVertices()
+	cmap = make([]int32, nvtxs)
+	ncoarse := int32(0)
+	for v := 0; v < nvtxs; v++ {
+		if int(match[v]) >= v {
+			cmap[v] = ncoarse
+			if int(match[v]) != v {
+				cmap[match[v]] = ncoarse
+			}
+			ncoarse++
+		}
+	}
+
+	return g.Contract(cmap, ncoarse), cmap
+}
+
+// Stopping rules of the METIS coarsening phase, used by EstimateCoarseningLevels
+const (
+	coarsenTo       = 20   // Coarsening stops below this many vertices
+	coarsenFraction = 0.85 // ...or when a level keeps more than this fraction
+)
+
+// EstimateCoarseningLevels estimates how many levels the multilevel hierarchy
+// of METIS has for g, by repeating heavy-edge matching until the graph is small
+// or a level no longer shrinks it by at least 15%, the rules METIS applies. It
+// is an estimate: METIS randomizes its matching, also matches vertices by
+// connectivity, and sizes the coarsest graph by nparts. Few levels on a large
+// graph mean coarsening stalls, as on stars, where only one edge per level can
+// be matched, which makes partitioning slow and poor.
+func EstimateCoarseningLevels(g *Graph) int {
+	levels := 0
+	for g.NumVertices() > coarsenTo {
+		coarse, _ := g.CoarsenHEM()
+		if float64(coarse.NumVertices()) > coarsenFraction*float64(g.NumVertices()) {
+			break
+		}
+		g = coarse
+		levels++
+	}
+	return levels
+}
+
+// HeavyEdgeMatching computes a maximal matching of g, the core step of METIS
+// coarsening and of CoarsenHEM, for experimenting with coarsening schemes.
+// Vertices are visited in order and each unmatched vertex is matched with the
+// unmatched neighbor joined by its heaviest edge, using unit weights when
+// Adjwgt is nil; ties go to the first neighbor in the adjacency list. METIS
+// visits vertices in random order instead. In the returned match, matched
+// vertices point to each other and unmatched vertices point to themselves.
+func (g *Graph) HeavyEdgeMatching() []int32 {
+	nvtxs := g.NumVertices()
+	match := make([]int32, nvtxs)
+	for v := range match {
+		match[v] = -1
+	}
+
+	for v := 0; v < nvtxs; v++ {
+		if match[v] != -1 {
+			continue
+		}
+
+		best := int32(v)
+		bestWgt := int32(-1)
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			u := g.Adjncy[j]
+			if u == int32(v) || match[u] != -1 {
+				continue
+			}
+			w := int32(1)
+			if g.Adjwgt != nil {
+				w = g.Adjwgt[j]
+			}
+			if w > bestWgt {
+				best, bestWgt = u, w
+			}
+		}
+
+		match[v] = best
+		match[best] = int32(v)
+	}
+
+	return match
+}
@@ -0,0 +1,109 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContract(t *testing.T) {
+	// Square 0-1-2-3-0 with weights on every edge
+	g := &Graph{
+		Xadj:   []int32{0, 2, 4, 6, 8},
+		Adjncy: []int32{1, 3, 0, 2, 1, 3, 0, 2},
+		Adjwgt: []int32{5, 1, 5, 2, 2, 3, 1, 3},
+	}
+
+	// Merge {0,1} and {2,3}: the two remaining edges become parallel
+	coarse := g.Contract([]int32{0, 0, 1, 1}, 2)
+	assert.Equal(t, []int32{0, 1, 2}, coarse.Xadj)
+	assert.Equal(t, []int32{1, 0}, coarse.Adjncy)
+	assert.Equal(t, []int32{3, 3}, coarse.Adjwgt)
+	assert.Equal(t, []int32{2, 2}, coarse.Vwgt)
+}
+
+func TestCoarsenHEM(t *testing.T) {
+	xadj, adjncy := createGridGraph(6, 6)
+	g := NewGraph(xadj, adjncy)
+
+	coarse, cmap := g.CoarsenHEM()
+	require.Len(t, cmap, 36)
+
+	ncoarse := coarse.NumVertices()
+	assert.Less(t, ncoarse, 36)
+	assert.GreaterOrEqual(t, ncoarse, 18)
+
+	// Each coarse vertex holds one or two fine vertices
+	total := int32(0)
+	for _, w := range coarse.Vwgt {
+		assert.True(t, w == 1 || w == 2)
+		total += w
+	}
+	assert.Equal(t, int32(36), total)
+
+	// Edge weight is preserved except for the contracted matching edges
+	fineEdges := int32(g.NumEdges())
+	coarseWgt := int32(0)
+	for _, w := range coarse.Adjwgt {
+		coarseWgt += w
+	}
+	assert.Equal(t, fineEdges-int32(36-ncoarse), coarseWgt/2)
+
+	// The coarse graph stays symmetric
+	for v := 0; v < ncoarse; v++ {
+		for j := coarse.Xadj[v]; j < coarse.Xadj[v+1]; j++ {
+			u := coarse.Adjncy[j]
+			assert.Contains(t, coarse.Neighbors(int(u)), int32(v))
+		}
+	}
+}
+
+func TestHeavyEdgeMatching(t *testing.T) {
+	for _, g := range []*Graph{
+		NewGraph(createGridGraph(7, 5)),
+		randomGraph(3, 60, 4, 2),
+	} {
+		match := g.HeavyEdgeMatching()
+		require.Len(t, match, g.NumVertices())
+		for v, u := range match {
+			// Matching is symmetric and along edges
+			assert.Equal(t, int32(v), match[u])
+			if int(u) != v {
+				assert.Contains(t, g.Neighbors(v), u)
+			}
+		}
+		// Maximal: no edge joins two unmatched vertices
+		g.EdgeIterator(func(u, v, _ int32) {
+			assert.False(t, match[u] == u && match[v] == v, "edge {%d, %d} could be matched", u, v)
+		})
+	}
+
+	// The center of a star takes its heaviest edge and leaves the rest unmatched
+	g := &Graph{Xadj: []int32{0, 3, 4, 5, 6}, Adjncy: []int32{1, 2, 3, 0, 0, 0}, Adjwgt: []int32{1, 5, 2, 1, 5, 2}}
+	assert.Equal(t, []int32{2, 1, 0, 3}, g.HeavyEdgeMatching())
+}
+
+func TestEstimateCoarseningLevels(t *testing.T) {
+	// A grid roughly halves per level: 1024 -> 512 -> ... -> 16
+	grid := NewGraph(createGridGraph(32, 32))
+	levels := EstimateCoarseningLevels(grid)
+	assert.GreaterOrEqual(t, levels, 5)
+	assert.LessOrEqual(t, levels, 7)
+
+	// A star can only match the hub once, so coarsening stalls immediately
+	n := int32(200)
+	xadj := []int32{0, n - 1}
+	adjncy := make([]int32, 0, 2*(n-1))
+	for v := int32(1); v < n; v++ {
+		adjncy = append(adjncy, v)
+	}
+	for v := int32(1); v < n; v++ {
+		adjncy = append(adjncy, 0)
+		xadj = append(xadj, xadj[len(xadj)-1]+1)
+	}
+	assert.Equal(t, 0, EstimateCoarseningLevels(NewGraph(xadj, adjncy)))
+
+	// Small graphs are not coarsened at all
+	assert.Equal(t, 0, EstimateCoarseningLevels(NewGraph(createGridGraph(4, 4))))
+}
@@ -0,0 +1,65 @@
+package metis
+
+import (
+	"fmt"
+	"sync"
+)
+
+// configMu serializes the METIS calls made through Config values
+var configMu sync.Mutex
+
+// Config is a reusable, immutable METIS configuration for server applications
+// that configure once and partition from many goroutines. It holds its own
+// copy of an options array, which cannot be changed after NewConfig returns,
+// so a Config may be shared freely. The METIS calls made through Config
+// methods are serialized by a package-level lock, since METIS itself is not
+// thread-safe; calls made directly through the package functions are not
+// covered by that lock.
+type Config struct {
+	options []int32
+}
+
+// NewConfig returns a Config holding a copy of options. nil selects the METIS
+// defaults.
+func NewConfig(options []int32) (*Config, error) {
+	opts := make([]int32, NoOptions)
+	if options == nil {
+		if err := SetDefaultOptions(opts); err != nil {
+			return nil, err
+		}
+		return &Config{options: opts}, nil
+	}
+
+	if len(options) != NoOptions {
+		return nil, fmt.Errorf("options must have %d elements, got %d", NoOptions, len(options))
+	}
+	copy(opts, options)
+	return &Config{options: opts}, nil
+}
+
+// Options returns a copy of the configured options array
+func (c *Config) Options() []int32 {
+	return append([]int32(nil), c.options...)
+}
+
+// PartitionGraph partitions g into nparts, as the package-level PartitionGraph
+func (c *Config) PartitionGraph(g *Graph, nparts int32) ([]int32, int32, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return PartitionGraph(g, nparts, c.Options())
+}
+
+// PartMeshDual partitions a mesh into nparts through its dual graph, returning
+// the objective value and the element and node partitions
+func (c *Config) PartMeshDual(ne, nn int32, eptr, eind, vwgt []int32, ncommon, nparts int32) (int32, []int32, []int32, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return PartMeshDual(ne, nn, eptr, eind, vwgt, nil, ncommon, nparts, nil, c.Options())
+}
+
+// NodeND computes a fill-reducing ordering, returning perm and iperm
+func (c *Config) NodeND(xadj, adjncy, vwgt []int32) ([]int32, []int32, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return NodeND(xadj, adjncy, vwgt, c.Options())
+}
@@ -0,0 +1,60 @@
+package metis
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+	opts[OptionSeed] = 7
+
+	c, err := NewConfig(opts)
+	require.NoError(t, err)
+
+	// Later changes to the caller's array or a returned copy do not leak in
+	opts[OptionSeed] = 8
+	c.Options()[OptionSeed] = 9
+	assert.Equal(t, int32(7), c.Options()[OptionSeed])
+
+	_, err = NewConfig(opts[:5])
+	assert.Error(t, err)
+
+	defaults, err := NewConfig(nil)
+	require.NoError(t, err)
+	assert.Len(t, defaults.Options(), NoOptions)
+}
+
+func TestConfigConcurrent(t *testing.T) {
+	c, err := NewConfig(nil)
+	require.NoError(t, err)
+
+	xadj, adjncy := createGridGraph(10, 10)
+	g := NewGraph(xadj, adjncy)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			part, objval, err := c.PartitionGraph(g, 4)
+			if err == nil {
+				err = VerifyPartition(xadj, adjncy, nil, nil, 4, objval, part)
+			}
+			if err == nil {
+				_, _, err = c.NodeND(xadj, adjncy, nil)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,106 @@
+package metis
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VertexWeightChange records a vertex whose weight differs between two graphs
+type VertexWeightChange struct {
+	Vertex   int32
+	Old, New int32
+}
+
+// EdgeWeightChange records an edge {U, V}, U < V, present in both graphs with
+// different weights
+type EdgeWeightChange struct {
+	U, V     int32
+	Old, New int32
+}
+
+// GraphDelta is the structural difference between two graphs computed by
+// GraphDiff. Edges are undirected pairs {u, v} with u < v, and every list is
+// sorted.
+type GraphDelta struct {
+	VerticesA, VerticesB int                  // Vertex counts of the two graphs
+	AddedEdges           [][2]int32           // Edges only in b
+	RemovedEdges         [][2]int32           // Edges only in a
+	VertexWeightChanges  []VertexWeightChange // Vertices of both graphs with different weights
+	EdgeWeightChanges    []EdgeWeightChange   // Edges of both graphs with different weights
+}
+
+// GraphDiff compares graph a to graph b, as before and after a preprocessing
+// step such as Normalize, FilterEdges or SymmetrizeEdgeWeights. Missing
+// weights count as unit weights, so dropping all-ones weight arrays is not a
+// change. Edges are read through EdgeIterator, from their lower endpoint; for
+// graphs with parallel edges only the last copy is compared, so normalize
+// both first when that matters. Vertex weight changes cover the vertices the
+// two graphs have in common.
+func GraphDiff(a, b *Graph) GraphDelta {
+	d := GraphDelta{VerticesA: a.NumVertices(), VerticesB: b.NumVertices()}
+
+	edgesA := make(map[[2]int32]int32)
+	a.EdgeIterator(func(u, v, w int32) { edgesA[[2]int32{u, v}] = w })
+	edgesB := make(map[[2]int32]int32)
+	b.EdgeIterator(func(u, v, w int32) { edgesB[[2]int32{u, v}] = w })
+
+	for e, wa := range edgesA {
+		wb, ok := edgesB[e]
+		if !ok {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+		} else if wa != wb {
+			d.EdgeWeightChanges = append(d.EdgeWeightChanges, EdgeWeightChange{U: e[0], V: e[1], Old: wa, New: wb})
+		}
+	}
+	for e := range edgesB {
+		if _, ok := edgesA[e]; !ok {
+			d.AddedEdges = append(d.AddedEdges, e)
+		}
+	}
+	sortEdgePairs(d.AddedEdges)
+	sortEdgePairs(d.RemovedEdges)
+	sort.Slice(d.EdgeWeightChanges, func(i, j int) bool {
+		ci, cj := d.EdgeWeightChanges[i], d.EdgeWeightChanges[j]
+		return ci.U < cj.U || ci.U == cj.U && ci.V < cj.V
+	})
+
+	common := d.VerticesA
+	if d.VerticesB < common {
+		common = d.VerticesB
+	}
+	for v := 0; v < common; v++ {
+		wa, wb := int32(1), int32(1)
+		if a.Vwgt != nil {
+			wa = a.Vwgt[v]
+		}
+		if b.Vwgt != nil {
+			wb = b.Vwgt[v]
+		}
+		if wa != wb {
+			d.VertexWeightChanges = append(d.VertexWeightChanges, VertexWeightChange{Vertex: int32(v), Old: wa, New: wb})
+		}
+	}
+
+	return d
+}
+
+// sortEdgePairs sorts edges by first and then second endpoint
+func sortEdgePairs(edges [][2]int32) {
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i][0] < edges[j][0] || edges[i][0] == edges[j][0] && edges[i][1] < edges[j][1]
+	})
+}
+
+// IsEmpty reports whether the two graphs compared are structurally identical
+func (d GraphDelta) IsEmpty() bool {
+	return d.VerticesA == d.VerticesB && len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 &&
+		len(d.VertexWeightChanges) == 0 && len(d.EdgeWeightChanges) == 0
+}
+
+// String summarizes the delta in one line of counts, short enough to log for
+// graphs of any size
+func (d GraphDelta) String() string {
+	return fmt.Sprintf("vertices %d -> %d, edges +%d -%d, vertex weights changed %d, edge weights changed %d",
+		d.VerticesA, d.VerticesB, len(d.AddedEdges), len(d.RemovedEdges),
+		len(d.VertexWeightChanges), len(d.EdgeWeightChanges))
+}
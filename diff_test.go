@@ -0,0 +1,51 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphDiff(t *testing.T) {
+	// Path 0-1-2-3 with weighted edges
+	a := &Graph{
+		Xadj:   []int32{0, 1, 3, 5, 6},
+		Adjncy: []int32{1, 0, 2, 1, 3, 2},
+		Adjwgt: []int32{4, 4, 2, 2, 1, 1},
+	}
+	assert.True(t, GraphDiff(a, a).IsEmpty())
+
+	// Drop the edges below weight 2
+	filtered := a.FilterEdges(2)
+	d := GraphDiff(a, filtered)
+	assert.Equal(t, [][2]int32{{2, 3}}, d.RemovedEdges)
+	assert.Empty(t, d.AddedEdges)
+	assert.False(t, d.IsEmpty())
+	assert.Equal(t, "vertices 4 -> 4, edges +0 -1, vertex weights changed 0, edge weights changed 0", d.String())
+
+	// Close the cycle, reweight an edge and a vertex
+	b := &Graph{
+		Xadj:   []int32{0, 2, 4, 6, 8},
+		Adjncy: []int32{1, 3, 0, 2, 1, 3, 2, 0},
+		Adjwgt: []int32{4, 1, 4, 5, 5, 1, 1, 1},
+		Vwgt:   []int32{1, 1, 3, 1},
+	}
+	d = GraphDiff(a, b)
+	assert.Equal(t, [][2]int32{{0, 3}}, d.AddedEdges)
+	assert.Empty(t, d.RemovedEdges)
+	assert.Equal(t, []EdgeWeightChange{{U: 1, V: 2, Old: 2, New: 5}}, d.EdgeWeightChanges)
+	assert.Equal(t, []VertexWeightChange{{Vertex: 2, Old: 1, New: 3}}, d.VertexWeightChanges)
+
+	// Unit weights are the same as no weights, zero weights are not
+	assert.False(t, GraphDiff(NewGraph(a.Xadj, a.Adjncy), &Graph{
+		Xadj: a.Xadj, Adjncy: a.Adjncy, Adjwgt: make([]int32, 6),
+	}).IsEmpty())
+	assert.True(t, GraphDiff(NewGraph(a.Xadj, a.Adjncy), &Graph{
+		Xadj: a.Xadj, Adjncy: a.Adjncy, Adjwgt: []int32{1, 1, 1, 1, 1, 1}, Vwgt: []int32{1, 1, 1, 1},
+	}).IsEmpty())
+
+	// Vertices beyond the smaller graph only show in the counts
+	d = GraphDiff(a, NewGraph([]int32{0, 1, 2}, []int32{1, 0}))
+	assert.Equal(t, 2, d.VerticesB)
+	assert.Equal(t, [][2]int32{{1, 2}, {2, 3}}, d.RemovedEdges)
+}
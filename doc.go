@@ -113,6 +113,17 @@ METIS behavior can be controlled through the options array:
 	opts[metis.OptionSeed] = 42                    // Random seed
 	opts[metis.OptionDBGLvl] = 0                   // Debug level
 
+The enumerated option values are typed: MethodKway is a PType, ObjectiveCut
+an ObjType, and so on for CType, IPType, RType and GType, each with a String
+method. The setters SetPType, SetObjType, SetCType, SetIPType, SetRType and
+SetGType only take a value of their own type, so giving OptionRType a
+coarsening scheme does not compile:
+
+	metis.SetRType(opts, metis.RefineFM)
+
+The untyped constants used above, such as PTypeKway, are deprecated but kept
+so that existing options arrays keep compiling.
+
 # Partitioning Methods
 
 Two main partitioning approaches are available:
@@ -163,21 +174,52 @@ All functions return errors for invalid inputs or internal failures:
 
 	part, edgeCut, err := metis.PartGraphKway(xadj, adjncy, nparts, opts)
 	if err != nil {
-		switch err {
-		case metis.ErrorInput:
+		var inputErr *metis.InputError
+		switch {
+		case errors.As(err, &inputErr):
+			// Invalid input, inputErr.Cause names the likely reason
+		case errors.Is(err, metis.ErrInput):
 			// Invalid input parameters
-		case metis.ErrorMemory:
+		case errors.Is(err, metis.ErrMemory):
 			// Insufficient memory
 		default:
 			// Other errors
 		}
 	}
 
+# Result Aliasing
+
+The top-level partitioning functions always return newly allocated slices.
+A Partitioner reuses its partition buffer between calls unless CopyResult is
+set, so the Part slice of a result it returns is overwritten by its next call.
+Use PartitionResult.Detach to keep such a result.
+
 # Thread Safety
 
 METIS functions are not thread-safe. Concurrent calls must be synchronized
 externally. For parallel partitioning, create separate METIS instances or
-use locking.
+use locking. A Config can be shared between goroutines and serializes the
+METIS calls made through it.
+
+A METIS library built with OpenMP parallelizes a single call internally.
+IsOpenMPEnabled reports whether the linked library is such a build, and
+SetThreadCount chooses its thread count for the calling OS thread, so lock
+the goroutine to its thread with runtime.LockOSThread around both.
+
+# Randomness
+
+The random choices made inside METIS, in matching and initial partitioning,
+are driven by its own generator, which OptionSeed seeds on every call; with a
+fixed seed, results are reproducible. The Go code of the package is
+deterministic; any routine of it that needs randomness takes an explicit seed
+and draws from a generator of its own rather than the global one in
+math/rand, so it is reproducible and safe to call from several goroutines.
+
+# Logging
+
+The package never prints. Retries, relaxed constraints and skipped outputs
+are reported through a Logger, which discards them unless SetLogger installs
+one that forwards to the host application's logging.
 
 # References
 
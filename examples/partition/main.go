@@ -256,28 +256,17 @@ func writeStatisticsFile(filename string, graph *metis.Graph, part []int32, npar
 func calculateCommunicationVolume(graph *metis.Graph, part []int32, nparts int32) int {
 	// Count unique partition pairs that communicate
 	commPairs := make(map[[2]int32]bool)
-	nvtxs := graph.NumVertices()
 
-	for i := 0; i < nvtxs; i++ {
-		myPart := part[i]
-		neighbors := make(map[int32]bool)
-
-		for j := graph.Xadj[i]; j < graph.Xadj[i+1]; j++ {
-			neighborPart := part[graph.Adjncy[j]]
-			if neighborPart != myPart {
-				neighbors[neighborPart] = true
-			}
+	graph.EdgeIterator(func(u, v, _ int32) {
+		pu, pv := part[u], part[v]
+		if pu == pv {
+			return
 		}
-
-		// Add communication pairs
-		for nPart := range neighbors {
-			if myPart < nPart {
-				commPairs[[2]int32{myPart, nPart}] = true
-			} else {
-				commPairs[[2]int32{nPart, myPart}] = true
-			}
+		if pu > pv {
+			pu, pv = pv, pu
 		}
-	}
+		commPairs[[2]int32{pu, pv}] = true
+	})
 
 	return len(commPairs)
 }
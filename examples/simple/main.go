@@ -21,6 +21,9 @@ func main() {
 
 	// Example 4: Nested dissection ordering
 	example4()
+
+	// Example 5: Partitioning with real-valued edge weights
+	example5()
 }
 
 func example1() {
@@ -254,3 +257,49 @@ func example4() {
 	}
 	fmt.Println()
 }
+
+func example5() {
+	fmt.Println("\nExample 5: Real-Valued Edge Weights")
+	fmt.Println("===================================")
+
+	// A resistor network of two well-conducting clusters {0,1,2} and {3,4,5}
+	// joined through the poor contacts 1-3 and 2-4:
+	//   0 - 1 ~ 3 - 5
+	//    \  |   |  /
+	//      2 ~ 4
+	xadj := []int32{0, 2, 5, 8, 11, 14, 16}
+	adjncy := []int32{
+		1, 2, // 0
+		0, 2, 3, // 1
+		0, 1, 4, // 2
+		1, 4, 5, // 3
+		2, 3, 5, // 4
+		3, 4, // 5
+	}
+
+	// Conductances in siemens, one per adjncy entry
+	conductance := []float64{
+		2.0, 1.5, // 0
+		2.0, 3.2, 0.02, // 1
+		1.5, 3.2, 0.05, // 2
+		0.02, 2.7, 1.1, // 3
+		0.05, 2.7, 4.0, // 4
+		1.1, 4.0, // 5
+	}
+
+	// METIS needs integer edge weights; scale the largest conductance to 1000
+	adjwgt := metis.ScaleEdgeWeights(conductance, 1000)
+	fmt.Printf("Scaled edge weights: %v\n", adjwgt)
+
+	opts := make([]int32, metis.NoOptions)
+	metis.SetDefaultOptions(opts)
+
+	part, edgeCut, err := metis.PartGraphKwayWeighted(xadj, adjncy, nil, adjwgt, 2, nil, nil, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Weighted edge cut: %d\n", edgeCut)
+	fmt.Printf("Partition assignment: %v\n", part)
+	fmt.Println("The cut should fall on the poor contacts 1-3 and 2-4")
+}
@@ -2,8 +2,13 @@ package metis
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -14,6 +19,13 @@ type Graph struct {
 	Adjncy []int32 // Adjacency lists (concatenated)
 	Vwgt   []int32 // Vertex weights (optional)
 	Adjwgt []int32 // Edge weights (optional)
+
+	// Labels names the vertices by their external ids (optional), so results
+	// can be reported in terms of the source data. METIS never sees them; they
+	// are carried by Subgraph, Normalize, FilterEdges, ConnectIsolated and
+	// RelabelBFS, and by encoding/json and encoding/gob, but not by the METIS
+	// file format. Equal ignores them; EqualLabels compares them.
+	Labels []string `json:",omitempty"`
 }
 
 // NewGraph creates a new graph from adjacency information
@@ -24,6 +36,15 @@ func NewGraph(xadj, adjncy []int32) *Graph {
 	}
 }
 
+// Label returns the label of vertex v, or v in decimal when g has no label
+// for it
+func (g *Graph) Label(v int) string {
+	if v >= 0 && v < len(g.Labels) {
+		return g.Labels[v]
+	}
+	return strconv.Itoa(v)
+}
+
 // NumVertices returns the number of vertices in the graph
 func (g *Graph) NumVertices() int {
 	return len(g.Xadj) - 1
@@ -46,193 +67,1892 @@ func (g *Graph) Neighbors(v int) []int32 {
 	return g.Adjncy[start:end]
 }
 
-// ConvertToMetisGraph converts a mesh to a METIS graph for partitioning
-func ConvertMeshToGraph(ne, nn int32, eptr, eind []int32, dual bool, ncommon int32) (*Graph, error) {
-	var xadj, adjncy []int32
-	var err error
+// EdgeIterator calls fn once for every undirected edge {u, v} of the graph,
+// with u < v, in increasing order of u and then adjacency order. weight is the
+// edge weight stored with u's copy of the edge, or 1 when Adjwgt is nil.
+// Self-loops are skipped. Both directions of every edge are assumed to be
+// stored, as METIS requires.
+func (g *Graph) EdgeIterator(fn func(u, v int32, weight int32)) {
+	for u := int32(0); int(u) < g.NumVertices(); u++ {
+		for j := g.Xadj[u]; j < g.Xadj[u+1]; j++ {
+			v := g.Adjncy[j]
+			if u >= v {
+				continue
+			}
+			if g.Adjwgt != nil {
+				fn(u, v, g.Adjwgt[j])
+			} else {
+				fn(u, v, 1)
+			}
+		}
+	}
+}
 
-	if dual {
-		xadj, adjncy, err = MeshToDual(ne, nn, eptr, eind, ncommon)
-	} else {
-		xadj, adjncy, err = MeshToNodal(ne, nn, eptr, eind)
+// Equal reports whether g and other have the same structure and weights. A
+// nil weight array only equals another nil or empty one. Labels are not
+// compared; see EqualLabels.
+func (g *Graph) Equal(other *Graph) bool {
+	if g == nil || other == nil {
+		return g == other
 	}
+	return equalInt32(g.Xadj, other.Xadj) &&
+		equalInt32(g.Adjncy, other.Adjncy) &&
+		equalInt32(g.Vwgt, other.Vwgt) &&
+		equalInt32(g.Adjwgt, other.Adjwgt)
+}
 
-	if err != nil {
-		return nil, err
+// EqualLabels reports whether g and other have the same labels. A nil label
+// array only equals another nil or empty one. Together with Equal it compares
+// labeled graphs in full.
+func (g *Graph) EqualLabels(other *Graph) bool {
+	if g == nil || other == nil {
+		return g == other
 	}
+	return equalStrings(g.Labels, other.Labels)
+}
 
-	return &Graph{
-		Xadj:   xadj,
-		Adjncy: adjncy,
-	}, nil
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
-// ReadGraphFile reads a graph in METIS format
-// Format:
-// Line 1: <# vertices> <# edges> [fmt] [ncon]
-// Following lines: vertex adjacency lists (and optional weights)
-func ReadGraphFile(r io.Reader) (*Graph, error) {
-	scanner := bufio.NewScanner(r)
+// DegreeSequence returns the degree of every vertex, indexed by vertex
+func (g *Graph) DegreeSequence() []int32 {
+	nvtxs := g.NumVertices()
+	if nvtxs <= 0 {
+		return nil
+	}
 
-	// Read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("empty file")
+	deg := make([]int32, nvtxs)
+	for i := range deg {
+		deg[i] = g.Xadj[i+1] - g.Xadj[i]
 	}
+	return deg
+}
 
-	header := strings.Fields(scanner.Text())
-	if len(header) < 2 {
-		return nil, fmt.Errorf("invalid header: %s", scanner.Text())
+// Assortativity returns the degree assortativity of the graph: the Pearson
+// correlation between the degrees at either end of an edge. Positive values
+// mean vertices attach to vertices of similar degree (meshes, grids); negative
+// values mean hubs attach to low-degree vertices (stars, power-law graphs),
+// which are typically harder to partition well. It returns NaN when the graph
+// has no edges or every edge joins vertices of equal degree.
+func (g *Graph) Assortativity() float64 {
+	deg := g.DegreeSequence()
+
+	// Each undirected edge is stored in both directions, so summing over
+	// adjacency entries makes the correlation symmetric
+	var n, sumX, sumXX, sumXY float64
+	for i, d := range deg {
+		x := float64(d)
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			y := float64(deg[g.Adjncy[j]])
+			n++
+			sumX += x
+			sumXX += x * x
+			sumXY += x * y
+		}
+	}
+	if n == 0 {
+		return math.NaN()
 	}
 
-	nvtxs, err := strconv.Atoi(header[0])
-	if err != nil {
-		return nil, fmt.Errorf("invalid number of vertices: %v", err)
+	mean := sumX / n
+	variance := sumXX/n - mean*mean
+	if variance <= 0 {
+		return math.NaN()
 	}
+	return (sumXY/n - mean*mean) / variance
+}
 
-	// nedges, err := strconv.Atoi(header[1])
-	// if err != nil {
-	// 	return nil, fmt.Errorf("invalid number of edges: %v", err)
-	// }
+// WeightWarning describes a suspicious vertex or edge weight
+type WeightWarning struct {
+	Vertex   int32 // Vertex owning the weight
+	Edge     int32 // Index into Adjncy/Adjwgt, or -1 for a vertex weight
+	Neighbor int32 // Other endpoint of the edge, or -1 for a vertex weight
+	Weight   int32 // The offending weight
+	IsError  bool  // Negative weights are invalid input for METIS
+}
 
-	// Parse format flags if present
-	hasVertexWeights := false
-	hasEdgeWeights := false
-	if len(header) >= 3 {
-		fmt, _ := strconv.Atoi(header[2])
-		hasVertexWeights = (fmt/10)%10 == 1
-		hasEdgeWeights = fmt%10 == 1
+// String describes the warning
+func (w WeightWarning) String() string {
+	level := "warning"
+	if w.IsError {
+		level = "error"
 	}
+	if w.Edge < 0 {
+		return fmt.Sprintf("%s: vertex %d has weight %d", level, w.Vertex, w.Weight)
+	}
+	return fmt.Sprintf("%s: edge %d (%d-%d) has weight %d", level, w.Edge, w.Vertex, w.Neighbor, w.Weight)
+}
 
-	// Read vertex data
-	xadj := make([]int32, nvtxs+1)
-	adjncy := []int32{}
-	vwgt := []int32{}
-	adjwgt := []int32{}
+// CheckWeights reports zero and negative vertex and edge weights. Zero vertex
+// weights make vertices free to pile onto any partition and zero edge weights
+// are ignored by the cut, which is rarely intended; negative weights are
+// invalid for METIS and are flagged as errors.
+func (g *Graph) CheckWeights() []WeightWarning {
+	var warnings []WeightWarning
+	nvtxs := g.NumVertices()
 
-	xadj[0] = 0
 	for i := 0; i < nvtxs; i++ {
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("unexpected EOF at vertex %d", i)
+		if g.Vwgt != nil && g.Vwgt[i] <= 0 {
+			warnings = append(warnings, WeightWarning{
+				Vertex:   int32(i),
+				Edge:     -1,
+				Neighbor: -1,
+				Weight:   g.Vwgt[i],
+				IsError:  g.Vwgt[i] < 0,
+			})
+		}
+		if g.Adjwgt == nil {
+			continue
+		}
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			if g.Adjwgt[j] <= 0 {
+				warnings = append(warnings, WeightWarning{
+					Vertex:   int32(i),
+					Edge:     j,
+					Neighbor: g.Adjncy[j],
+					Weight:   g.Adjwgt[j],
+					IsError:  g.Adjwgt[j] < 0,
+				})
+			}
 		}
+	}
 
-		fields := strings.Fields(scanner.Text())
-		if len(fields) == 0 {
-			continue // Empty adjacency list
+	return warnings
+}
+
+// CountParallelEdges returns the number of redundant copies of edges: an
+// edge listed k times in its endpoints' adjacency lists counts k-1. Parallel
+// edges are accepted by METIS but multiply the edge's share of the cut and
+// inflate vertex degrees, so a non-zero count usually means the input should
+// go through Normalize. Self-loops are not counted. Adjacency lists need not
+// be sorted.
+func (g *Graph) CountParallelEdges() int {
+	nvtxs := g.NumVertices()
+	if nvtxs <= 0 {
+		return 0
+	}
+
+	// mark[u] == v+1 records that u was already seen in v's list
+	mark := make([]int32, nvtxs)
+	count := 0
+	for v := 0; v < nvtxs; v++ {
+		for _, u := range g.Neighbors(v) {
+			if int(u) <= v {
+				continue
+			}
+			if mark[u] == int32(v)+1 {
+				count++
+			}
+			mark[u] = int32(v) + 1
 		}
+	}
+	return count
+}
 
-		fieldIdx := 0
+// Normalize returns a copy of g in canonical form: adjacency lists sorted
+// by neighbor, self-loops removed and parallel edges merged into one whose
+// weight is the sum of theirs. Graphs built from edge lists or merged data
+// sources often need this before partitioning.
+func (g *Graph) Normalize() *Graph {
+	nvtxs := g.NumVertices()
+	if nvtxs < 0 {
+		nvtxs = 0
+	}
 
-		// Read vertex weight if present
-		if hasVertexWeights {
-			w, err := strconv.Atoi(fields[fieldIdx])
-			if err != nil {
-				return nil, fmt.Errorf("invalid vertex weight at vertex %d: %v", i, err)
+	out := &Graph{Xadj: make([]int32, nvtxs+1), Adjncy: make([]int32, 0, len(g.Adjncy))}
+	if g.Vwgt != nil {
+		out.Vwgt = append([]int32(nil), g.Vwgt...)
+	}
+	if g.Adjwgt != nil {
+		out.Adjwgt = make([]int32, 0, len(g.Adjncy))
+	}
+	if g.Labels != nil {
+		out.Labels = append([]string(nil), g.Labels...)
+	}
+
+	// order holds the positions of v's non-loop entries, sorted by neighbor
+	var order []int32
+	for v := 0; v < nvtxs; v++ {
+		order = order[:0]
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			if g.Adjncy[j] != int32(v) {
+				order = append(order, j)
 			}
-			vwgt = append(vwgt, int32(w))
-			fieldIdx++
 		}
+		sort.SliceStable(order, func(a, b int) bool { return g.Adjncy[order[a]] < g.Adjncy[order[b]] })
 
-		// Read adjacency list
-		for j := fieldIdx; j < len(fields); j++ {
-			if hasEdgeWeights && (j-fieldIdx)%2 == 1 {
-				// This is an edge weight
-				w, err := strconv.Atoi(fields[j])
-				if err != nil {
-					return nil, fmt.Errorf("invalid edge weight at vertex %d: %v", i, err)
-				}
-				adjwgt = append(adjwgt, int32(w))
-			} else {
-				// This is a vertex
-				v, err := strconv.Atoi(fields[j])
-				if err != nil {
-					return nil, fmt.Errorf("invalid vertex id at vertex %d: %v", i, err)
+		start := len(out.Adjncy)
+		for _, j := range order {
+			u := g.Adjncy[j]
+			if len(out.Adjncy) > start && out.Adjncy[len(out.Adjncy)-1] == u {
+				if g.Adjwgt != nil {
+					out.Adjwgt[len(out.Adjwgt)-1] += g.Adjwgt[j]
 				}
-				// Convert to 0-based indexing
-				adjncy = append(adjncy, int32(v-1))
+				continue
+			}
+			out.Adjncy = append(out.Adjncy, u)
+			if g.Adjwgt != nil {
+				out.Adjwgt = append(out.Adjwgt, g.Adjwgt[j])
 			}
 		}
+		out.Xadj[v+1] = int32(len(out.Adjncy))
+	}
 
-		xadj[i+1] = int32(len(adjncy))
+	return out
+}
+
+// StripSelfLoops removes, in place, every adjacency entry of a vertex to
+// itself and returns how many were removed. Graphs derived from matrices
+// often carry the diagonal as self-loops, which METIS either rejects or lets
+// skew vertex degrees and edge weights. Call it right after reading such a
+// file; the number removed is also reported to the Logger.
+func (g *Graph) StripSelfLoops() int {
+	nvtxs := g.NumVertices()
+	removed := 0
+	k := int32(0)
+	for v := 0; v < nvtxs; v++ {
+		start, end := g.Xadj[v], g.Xadj[v+1]
+		g.Xadj[v] = k
+		for j := start; j < end; j++ {
+			if g.Adjncy[j] == int32(v) {
+				removed++
+				continue
+			}
+			g.Adjncy[k] = g.Adjncy[j]
+			if g.Adjwgt != nil {
+				g.Adjwgt[k] = g.Adjwgt[j]
+			}
+			k++
+		}
+	}
+	if removed == 0 {
+		return 0
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %v", err)
+	g.Xadj[nvtxs] = k
+	g.Adjncy = g.Adjncy[:k]
+	if g.Adjwgt != nil {
+		g.Adjwgt = g.Adjwgt[:k]
 	}
+	warnf("StripSelfLoops: removed %d self-loops", removed)
+	return removed
+}
 
-	g := &Graph{
-		Xadj:   xadj,
-		Adjncy: adjncy,
+// FilterEdges returns a new graph without the edges whose weight is below
+// minWeight, which sharpens community structure before partitioning weighted
+// graphs. An edge is removed in both directions if either direction is below
+// the threshold, so a symmetric graph stays symmetric. Vertices that lose all
+// their edges are kept with an empty adjacency list. Unweighted graphs are
+// treated as having unit edge weights.
+func (g *Graph) FilterEdges(minWeight int32) *Graph {
+	nvtxs := g.NumVertices()
+	weight := func(j int32) int32 {
+		if g.Adjwgt == nil {
+			return 1
+		}
+		return g.Adjwgt[j]
+	}
+
+	// Index every directed edge so the reverse direction can be checked
+	index := edgeIndex(g.Xadj, g.Adjncy)
+
+	filtered := &Graph{
+		Xadj:   make([]int32, nvtxs+1),
+		Adjncy: []int32{},
+	}
+	if g.Vwgt != nil {
+		filtered.Vwgt = append([]int32(nil), g.Vwgt...)
+	}
+	if g.Labels != nil {
+		filtered.Labels = append([]string(nil), g.Labels...)
+	}
+	if g.Adjwgt != nil {
+		filtered.Adjwgt = []int32{}
+	}
+
+	for i := 0; i < nvtxs; i++ {
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			v := g.Adjncy[j]
+			if weight(j) < minWeight {
+				continue
+			}
+			if r, ok := index[[2]int32{v, int32(i)}]; ok && weight(r) < minWeight {
+				continue
+			}
+			filtered.Adjncy = append(filtered.Adjncy, v)
+			if g.Adjwgt != nil {
+				filtered.Adjwgt = append(filtered.Adjwgt, g.Adjwgt[j])
+			}
+		}
+		filtered.Xadj[i+1] = int32(len(filtered.Adjncy))
+	}
+
+	return filtered
+}
+
+// edgeIndex maps every directed edge {u, v} of a CSR graph to its position in
+// adjncy, for looking up the reverse direction of an edge
+func edgeIndex(xadj, adjncy []int32) map[[2]int32]int32 {
+	index := make(map[[2]int32]int32, len(adjncy))
+	for i := 0; i+1 < len(xadj); i++ {
+		for j := xadj[i]; j < xadj[i+1]; j++ {
+			index[[2]int32{int32(i), adjncy[j]}] = j
+		}
+	}
+	return index
+}
+
+// WeightPolicy selects how SymmetrizeEdgeWeights reconciles the two weights
+// stored for an undirected edge
+type WeightPolicy int
+
+const (
+	WeightPolicyMax  WeightPolicy = iota // Keep the larger weight
+	WeightPolicyMin                      // Keep the smaller weight
+	WeightPolicyMean                     // Average the weights, rounding down but at least 1
+	WeightPolicySum                      // Add the weights
+)
+
+// SymmetrizeEdgeWeights returns a copy of adjwgt in which both directions of
+// every edge carry the same weight, combined according to policy. METIS
+// assumes adjwgt[u->v] == adjwgt[v->u]; asymmetric weights are not rejected
+// but silently bias the cut. Edges whose reverse direction is missing keep
+// their weight.
+func SymmetrizeEdgeWeights(xadj, adjncy, adjwgt []int32, policy WeightPolicy) ([]int32, error) {
+	if len(adjwgt) != len(adjncy) {
+		return nil, fmt.Errorf("adjwgt length %d does not match adjncy length %d", len(adjwgt), len(adjncy))
+	}
+
+	combine, err := weightCombiner(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	index := edgeIndex(xadj, adjncy)
+	sym := append([]int32(nil), adjwgt...)
+	for i := 0; i+1 < len(xadj); i++ {
+		for j := xadj[i]; j < xadj[i+1]; j++ {
+			v := adjncy[j]
+			if r, ok := index[[2]int32{v, int32(i)}]; ok && int32(i) < v {
+				w := combine(adjwgt[j], adjwgt[r])
+				sym[j], sym[r] = w, w
+			}
+		}
+	}
+
+	return sym, nil
+}
+
+// DirectedDegrees treats g as a directed graph, with adjncy listing the
+// targets of each vertex's outgoing edges, and returns the in- and out-degree
+// of every vertex. For a properly undirected graph the two are equal; a
+// mismatch shows where edges are missing their reverse direction, which
+// Symmetrize or PartitionDirected repair. Self-loops count once in each.
+func (g *Graph) DirectedDegrees() (in, out []int32) {
+	nvtxs := g.NumVertices()
+	in = make([]int32, nvtxs)
+	out = make([]int32, nvtxs)
+	for v := 0; v < nvtxs; v++ {
+		out[v] = g.Xadj[v+1] - g.Xadj[v]
+		for _, u := range g.Neighbors(v) {
+			in[u]++
+		}
+	}
+	return in, out
+}
+
+// weightCombiner returns the function that merges the two weights of an edge
+// under policy
+func weightCombiner(policy WeightPolicy) (func(a, b int32) int32, error) {
+	switch policy {
+	case WeightPolicyMax:
+		return func(a, b int32) int32 {
+			if a > b {
+				return a
+			}
+			return b
+		}, nil
+	case WeightPolicyMin:
+		return func(a, b int32) int32 {
+			if a < b {
+				return a
+			}
+			return b
+		}, nil
+	case WeightPolicyMean:
+		return func(a, b int32) int32 {
+			if m := int32((int64(a) + int64(b)) / 2); m > 0 {
+				return m
+			}
+			return 1
+		}, nil
+	case WeightPolicySum:
+		return func(a, b int32) int32 { return a + b }, nil
+	}
+	return nil, fmt.Errorf("unknown weight policy %d", policy)
+}
+
+// Symmetrize turns a directed graph in CSR form into the undirected graph
+// METIS expects: every edge u->v also appears as v->u. When both directions
+// are present their weights are combined according to policy; an edge given in
+// one direction only keeps its weight. Repeated edges in the same direction
+// are merged by adding their weights, and self-loops are dropped. adjwgt may be
+// nil, in which case the result is unweighted. Adjacency lists of the result
+// are sorted.
+func Symmetrize(xadj, adjncy, adjwgt []int32, policy WeightPolicy) (*Graph, error) {
+	combine, err := weightCombiner(policy)
+	if err != nil {
+		return nil, err
+	}
+	if len(xadj) == 0 {
+		return nil, fmt.Errorf("xadj must have at least one element")
+	}
+	if adjwgt != nil && len(adjwgt) != len(adjncy) {
+		return nil, fmt.Errorf("adjwgt length %d does not match adjncy length %d", len(adjwgt), len(adjncy))
+	}
+	nvtxs := int32(len(xadj) - 1)
+	if int(xadj[nvtxs]) != len(adjncy) {
+		return nil, fmt.Errorf("xadj[%d]=%d does not match adjncy length %d", nvtxs, xadj[nvtxs], len(adjncy))
 	}
 
-	if hasVertexWeights {
-		g.Vwgt = vwgt
+	// Weights of each unordered pair {u, v}, u < v, by direction
+	type pairWeights struct {
+		up, down       int32 // u->v and v->u
+		hasUp, hasDown bool
+	}
+	pairs := make(map[[2]int32]*pairWeights)
+	for u := int32(0); u < nvtxs; u++ {
+		for j := xadj[u]; j < xadj[u+1]; j++ {
+			v := adjncy[j]
+			if v < 0 || v >= nvtxs {
+				return nil, fmt.Errorf("vertex %d has neighbor %d out of range [0, %d)", u, v, nvtxs)
+			}
+			if v == u {
+				continue
+			}
+			w := int32(1)
+			if adjwgt != nil {
+				w = adjwgt[j]
+			}
+			key := [2]int32{u, v}
+			if v < u {
+				key = [2]int32{v, u}
+			}
+			pw := pairs[key]
+			if pw == nil {
+				pw = &pairWeights{}
+				pairs[key] = pw
+			}
+			if u < v {
+				pw.up += w
+				pw.hasUp = true
+			} else {
+				pw.down += w
+				pw.hasDown = true
+			}
+		}
 	}
-	if hasEdgeWeights {
-		g.Adjwgt = adjwgt
+
+	type neighbor struct{ v, w int32 }
+	adj := make([][]neighbor, nvtxs)
+	for key, pw := range pairs {
+		u, v := key[0], key[1]
+		w := pw.up
+		switch {
+		case pw.hasUp && pw.hasDown:
+			w = combine(pw.up, pw.down)
+		case pw.hasDown:
+			w = pw.down
+		}
+		adj[u] = append(adj[u], neighbor{v, w})
+		adj[v] = append(adj[v], neighbor{u, w})
 	}
 
+	g := &Graph{Xadj: make([]int32, nvtxs+1), Adjncy: make([]int32, 0, 2*len(pairs))}
+	if adjwgt != nil {
+		g.Adjwgt = make([]int32, 0, 2*len(pairs))
+	}
+	for u := int32(0); u < nvtxs; u++ {
+		nbrs := adj[u]
+		sort.Slice(nbrs, func(a, b int) bool { return nbrs[a].v < nbrs[b].v })
+		for _, n := range nbrs {
+			g.Adjncy = append(g.Adjncy, n.v)
+			if adjwgt != nil {
+				g.Adjwgt = append(g.Adjwgt, n.w)
+			}
+		}
+		g.Xadj[u+1] = int32(len(g.Adjncy))
+	}
 	return g, nil
 }
 
-// WritePartitioning writes partition information to a writer
-func WritePartitioning(w io.Writer, part []int32) error {
-	for _, p := range part {
-		if _, err := fmt.Fprintf(w, "%d\n", p); err != nil {
-			return err
+// ScaleEdgeWeights quantizes real-valued edge weights, such as physical
+// conductances or coupling coefficients, into the positive integers METIS
+// requires. Weights are scaled in proportion to the largest one, which maps to
+// targetMax, and rounded to the nearest integer; results below 1, including
+// those of zero, negative and NaN weights, are raised to 1 because METIS
+// rejects non-positive edge weights. Ratios between weights are preserved only
+// to within 1/targetMax of the largest weight: weights smaller than
+// 1/(2*targetMax) of the maximum all become 1 and lose their ordering. A
+// targetMax of 1000 or so keeps that loss small while leaving room for METIS
+// to sum weights without overflow. It returns nil if targetMax < 1.
+func ScaleEdgeWeights(weights []float64, targetMax int32) []int32 {
+	if targetMax < 1 {
+		return nil
+	}
+
+	maxWeight := 0.0
+	for _, w := range weights {
+		if w > maxWeight {
+			maxWeight = w
 		}
 	}
-	return nil
+
+	scaled := make([]int32, len(weights))
+	for i, w := range weights {
+		scaled[i] = 1
+		if w > 0 && maxWeight > 0 {
+			if q := int32(math.Round(w / maxWeight * float64(targetMax))); q > 1 {
+				scaled[i] = q
+			}
+		}
+	}
+	return scaled
 }
 
-// CalculateEdgeCut calculates the edge cut for a given partitioning
-func CalculateEdgeCut(g *Graph, part []int32) int32 {
-	edgeCut := int32(0)
+// GeometricEdgeWeights computes edge weights from the Euclidean distance
+// between the endpoints of each edge, using fn to turn a distance into a
+// weight (for example an inverse length for tighter coupling of short edges).
+// coords holds one position per vertex.
+//
+// The result is aligned with g.Adjncy: entry j is the weight of the edge
+// stored at g.Adjncy[j], so it can be assigned directly to g.Adjwgt. Since
+// distances are symmetric, both directions of an edge receive the same weight
+// as long as fn is deterministic.
+func GeometricEdgeWeights(g *Graph, coords [][3]float64, fn func(d float64) int32) []int32 {
+	adjwgt := make([]int32, len(g.Adjncy))
 	nvtxs := g.NumVertices()
 
 	for i := 0; i < nvtxs; i++ {
 		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
-			neighbor := g.Adjncy[j]
-			if part[i] != part[neighbor] {
-				if g.Adjwgt != nil {
-					edgeCut += g.Adjwgt[j]
-				} else {
-					edgeCut++
-				}
-			}
+			a, b := coords[i], coords[g.Adjncy[j]]
+			dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+			adjwgt[j] = fn(math.Sqrt(dx*dx + dy*dy + dz*dz))
 		}
 	}
 
-	return edgeCut / 2 // Each edge counted twice
+	return adjwgt
 }
 
-// CalculatePartitionBalance calculates partition balance statistics
-func CalculatePartitionBalance(part []int32, vwgt []int32, nparts int32) (min, max, avg float64) {
-	partWeights := make([]int64, nparts)
-	nvtxs := len(part)
+// LaplacianCSR returns the weighted graph Laplacian L = D - A in CSR form,
+// where D holds the weighted vertex degrees and A the edge weights (unit
+// weights when Adjwgt is nil). Each row stores the diagonal entry followed by
+// one -weight entry per neighbor in adjacency order. Self-loops do not
+// contribute to the Laplacian and are skipped.
+func (g *Graph) LaplacianCSR() (xadj, adjncy []int32, values []float64) {
+	nvtxs := g.NumVertices()
+	xadj = make([]int32, nvtxs+1)
+	adjncy = make([]int32, 0, len(g.Adjncy)+nvtxs)
+	values = make([]float64, 0, len(g.Adjncy)+nvtxs)
 
 	for i := 0; i < nvtxs; i++ {
-		weight := int64(1)
-		if vwgt != nil && i < len(vwgt) {
-			weight = int64(vwgt[i])
+		diag := len(values)
+		adjncy = append(adjncy, int32(i))
+		values = append(values, 0)
+
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			if g.Adjncy[j] == int32(i) {
+				continue
+			}
+			w := 1.0
+			if g.Adjwgt != nil {
+				w = float64(g.Adjwgt[j])
+			}
+			adjncy = append(adjncy, g.Adjncy[j])
+			values = append(values, -w)
+			values[diag] += w
 		}
-		partWeights[part[i]] += weight
+
+		xadj[i+1] = int32(len(adjncy))
 	}
 
-	totalWeight := int64(0)
-	minWeight := partWeights[0]
-	maxWeight := partWeights[0]
+	return xadj, adjncy, values
+}
 
-	for _, w := range partWeights {
-		totalWeight += w
-		if w < minWeight {
-			minWeight = w
+// MaxDenseVertices is the largest graph ToDenseMatrix will expand
+const MaxDenseVertices = 4096
+
+// ToDenseMatrix returns the adjacency matrix as an nvtxs x nvtxs dense
+// matrix, with edge weights as values (1 when Adjwgt is nil). A dense matrix
+// needs nvtxs² entries, so it is only meant for small graphs: graphs with more
+// than MaxDenseVertices vertices return nil. Use ToTriplets for anything
+// larger.
+func (g *Graph) ToDenseMatrix() [][]int32 {
+	nvtxs := g.NumVertices()
+	if nvtxs > MaxDenseVertices {
+		return nil
+	}
+
+	dense := make([][]int32, nvtxs)
+	for i := range dense {
+		dense[i] = make([]int32, nvtxs)
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			if g.Adjwgt != nil {
+				dense[i][g.Adjncy[j]] += g.Adjwgt[j]
+			} else {
+				dense[i][g.Adjncy[j]]++
+			}
 		}
-		if w > maxWeight {
-			maxWeight = w
+	}
+
+	return dense
+}
+
+// ToTriplets returns the adjacency matrix in coordinate (COO) form, one
+// (row, col, val) triplet per stored directed edge in CSR order, with edge
+// weights as values (1 when Adjwgt is nil).
+func (g *Graph) ToTriplets() (rows, cols, vals []int32) {
+	nnz := len(g.Adjncy)
+	rows = make([]int32, nnz)
+	cols = make([]int32, nnz)
+	vals = make([]int32, nnz)
+
+	for i := 0; i < g.NumVertices(); i++ {
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			rows[j] = int32(i)
+			cols[j] = g.Adjncy[j]
+			vals[j] = 1
+			if g.Adjwgt != nil {
+				vals[j] = g.Adjwgt[j]
+			}
 		}
 	}
 
-	avgWeight := float64(totalWeight) / float64(nparts)
+	return rows, cols, vals
+}
 
-	return float64(minWeight), float64(maxWeight), avgWeight
+// ConvertMeshToGraph converts a mesh to a METIS graph for partitioning.
+// vwgt, if non-nil, is attached as the graph's vertex weights: for the dual
+// graph vertex i is element i, so vwgt holds ne element weights; for the nodal
+// graph vertex i is node i, so vwgt holds nn node weights.
+func ConvertMeshToGraph(ne, nn int32, eptr, eind []int32, vwgt []int32, dual bool, ncommon int32) (*Graph, error) {
+	nvtxs := nn
+	if dual {
+		nvtxs = ne
+	}
+	if vwgt != nil && len(vwgt) != int(nvtxs) {
+		return nil, fmt.Errorf("vwgt length %d does not match %d graph vertices", len(vwgt), nvtxs)
+	}
+
+	var xadj, adjncy []int32
+	var err error
+
+	if dual {
+		xadj, adjncy, err = MeshToDual(ne, nn, eptr, eind, ncommon)
+	} else {
+		xadj, adjncy, err = MeshToNodal(ne, nn, eptr, eind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{
+		Xadj:   xadj,
+		Adjncy: adjncy,
+	}
+	if vwgt != nil {
+		g.Vwgt = append([]int32(nil), vwgt...)
+	}
+
+	return g, nil
+}
+
+// ReadGraphFile reads a graph in METIS format
+// Format:
+// Line 1: <# vertices> <# edges> [fmt] [ncon]
+// Following lines: vertex adjacency lists (and optional weights)
+//
+// Lines starting with %, possibly indented, are comments. A blank line is the
+// empty adjacency list of an isolated vertex, except when the file has more
+// lines than vertices and exactly one non-blank line per vertex: blank lines
+// are then strays between vertices and are skipped. The file is read to its
+// end to make that decision, but streamed: only the parsed vertices are kept.
+// Vertex sizes (fmt 1xx) and more than one vertex weight per vertex (ncon > 1)
+// cannot be represented by Graph and are rejected.
+func ReadGraphFile(r io.Reader) (*Graph, error) {
+	lines := newGraphLineReader(r)
+
+	// Read header
+	nvtxs, format, err := lines.header()
+	if err != nil {
+		return nil, err
+	}
+	layout, err := metisLineLayout(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return lines.metisAdjacency(nvtxs, layout)
+}
+
+// metisLineLayout parses the fmt and ncon fields of a METIS graph file header
+func metisLineLayout(format []string) (graphLineLayout, error) {
+	var layout graphLineLayout
+	if len(format) >= 1 {
+		code, err := strconv.Atoi(format[0])
+		if err != nil || code < 0 {
+			return layout, fmt.Errorf("invalid format: %s", format[0])
+		}
+		if code/100 != 0 {
+			return layout, fmt.Errorf("vertex sizes (format %s) are not supported", format[0])
+		}
+		layout.vertexWeights = (code/10)%10 == 1
+		layout.edgeWeights = code%10 == 1
+	}
+	if len(format) >= 2 {
+		ncon, err := strconv.Atoi(format[1])
+		if err != nil {
+			return layout, fmt.Errorf("invalid ncon: %s", format[1])
+		}
+		if ncon != 1 {
+			return layout, fmt.Errorf("ncon %d is not supported, only single-constraint graphs", ncon)
+		}
+	}
+	return layout, nil
+}
+
+// ReadGraphFileCSR reads a METIS graph file like ReadGraphFile, but in two
+// passes over the file to keep peak memory down on huge graphs. The first
+// only counts the neighbors on every vertex line to size xadj, adjncy and the
+// weight arrays exactly, and classifies blank lines as ReadGraphFile does; the
+// second parses the numbers straight into them. This avoids the growing
+// appends and per-line field slices of ReadGraphFile, whose peak is several
+// times the size of the final graph, at the cost of reading the file twice.
+func ReadGraphFileCSR(path string) (*Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pass 1: header, degrees and blank lines
+	lines := newGraphLineReader(f)
+	nvtxs, format, err := lines.header()
+	if err != nil {
+		return nil, err
+	}
+	layout, err := metisLineLayout(format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every vertex takes at least a newline, which bounds a bogus header
+	if int64(nvtxs) > info.Size() {
+		return nil, fmt.Errorf("header declares %d vertices, more than the file can hold", nvtxs)
+	}
+
+	// Until blank lines are classified, xadj accumulates the degrees of the
+	// non-blank lines
+	xadj := make([]int32, nvtxs+1)
+	count := newVertexLineCount(nvtxs)
+	var failed error
+	for !count.done() && lines.next() {
+		line := lines.scanner.Bytes()
+		row, parse := count.add(line)
+		if !parse {
+			continue
+		}
+		degree, err := layout.degree(line)
+		if err == nil && int64(xadj[row])+int64(degree) > math.MaxInt32 {
+			err = fmt.Errorf("too many edges for 32-bit indices")
+		}
+		if err != nil {
+			failed = err
+			count.fail(row)
+			continue
+		}
+		xadj[row+1] = xadj[row] + degree
+	}
+	if err := lines.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	err = count.classify(
+		func(i int) error { return fmt.Errorf("vertex %d: %v", i, failed) },
+		func(i int) error {
+			if _, err := layout.degree(nil); err != nil {
+				return fmt.Errorf("vertex %d: %v", i, err)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	xadj = count.vertexXadj(xadj)
+
+	// Pass 2: adjacency and weights
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	lines = newGraphLineReader(f)
+	lines.skipBlank = count.strays()
+	if _, _, err := lines.header(); err != nil {
+		return nil, err
+	}
+
+	g := &Graph{
+		Xadj:   xadj,
+		Adjncy: make([]int32, xadj[nvtxs]),
+	}
+	if layout.vertexWeights {
+		g.Vwgt = make([]int32, nvtxs)
+	}
+	if layout.edgeWeights {
+		g.Adjwgt = make([]int32, xadj[nvtxs])
+	}
+
+	for i := 0; i < nvtxs; i++ {
+		if !lines.next() {
+			return nil, fmt.Errorf("unexpected EOF at vertex %d", i)
+		}
+		if err := layout.fill(g, i, lines.scanner.Bytes()); err != nil {
+			return nil, fmt.Errorf("vertex %d: %v", i, err)
+		}
+	}
+	if err := lines.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	return g, nil
+}
+
+// degree counts the neighbors listed on a vertex line
+func (layout graphLineLayout) degree(line []byte) (int32, error) {
+	fields := 0
+	for rest := line; ; fields++ {
+		var field []byte
+		if field, rest = nextField(rest); field == nil {
+			break
+		}
+	}
+
+	if layout.vertexNumbers {
+		fields--
+	}
+	if layout.vertexWeights {
+		fields--
+	}
+	if fields < 0 {
+		return 0, fmt.Errorf("missing vertex weight")
+	}
+	if layout.edgeWeights {
+		if fields%2 != 0 {
+			return 0, fmt.Errorf("edge without weight")
+		}
+		fields /= 2
+	}
+	return int32(fields), nil
+}
+
+// fill parses vertex line i into the preallocated arrays of g
+func (layout graphLineLayout) fill(g *Graph, i int, line []byte) error {
+	nvtxs := int32(len(g.Xadj) - 1)
+
+	field, rest := nextField(line)
+	if layout.vertexWeights {
+		w, ok := parseInt32(field)
+		if !ok {
+			return fmt.Errorf("invalid vertex weight %q", field)
+		}
+		g.Vwgt[i] = w
+		field, rest = nextField(rest)
+	}
+
+	j := g.Xadj[i]
+	for ; field != nil; field, rest = nextField(rest) {
+		if j >= g.Xadj[i+1] {
+			return fmt.Errorf("file changed between passes")
+		}
+		v, ok := parseInt32(field)
+		if !ok || v < 1 || v > nvtxs {
+			return fmt.Errorf("invalid vertex id %q", field)
+		}
+		g.Adjncy[j] = v - 1
+
+		if layout.edgeWeights {
+			field, rest = nextField(rest)
+			w, ok := parseInt32(field)
+			if !ok {
+				return fmt.Errorf("invalid edge weight %q", field)
+			}
+			g.Adjwgt[j] = w
+		}
+		j++
+	}
+	if j != g.Xadj[i+1] {
+		return fmt.Errorf("file changed between passes")
+	}
+	return nil
+}
+
+// nextField returns the first whitespace-separated field of b and the rest of
+// b after it, or a nil field when b holds no more fields
+func nextField(b []byte) (field, rest []byte) {
+	start := 0
+	for start < len(b) && isFieldSpace(b[start]) {
+		start++
+	}
+	if start == len(b) {
+		return nil, nil
+	}
+	end := start
+	for end < len(b) && !isFieldSpace(b[end]) {
+		end++
+	}
+	return b[start:end], b[end:]
+}
+
+func isFieldSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// parseInt32 parses a signed decimal integer without allocating
+func parseInt32(b []byte) (int32, bool) {
+	negative := false
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		negative = b[0] == '-'
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return 0, false
+	}
+	v, ok := parseVertexID(b)
+	if negative {
+		v = -v
+	}
+	return v, ok
+}
+
+// ReadChacoFile reads a graph in the Chaco format, the predecessor of the METIS
+// format. The layout is the same, one header line followed by one line per
+// vertex with 1-based neighbor ids, but the conventions differ:
+//   - the header is <# vertices> <# edges> [code], where code is up to three
+//     digits: 100 means every vertex line starts with its own vertex number
+//     (METIS uses this digit for vertex sizes), 10 vertex weights and 1 edge
+//     weights
+//   - edge weights may be real numbers; they are rounded to the nearest
+//     integer, as METIS only takes integer weights
+//   - there is no ncon field, so a fourth header entry is rejected
+//
+// Lines starting with % are comments in both formats.
+func ReadChacoFile(r io.Reader) (*Graph, error) {
+	lines := newGraphLineReader(r)
+
+	nvtxs, format, err := lines.header()
+	if err != nil {
+		return nil, err
+	}
+	if len(format) > 1 {
+		return nil, fmt.Errorf("unexpected header field %q in Chaco file", format[1])
+	}
+
+	layout := graphLineLayout{realEdgeWeights: true}
+	if len(format) == 1 {
+		code, err := strconv.Atoi(format[0])
+		if err != nil || code < 0 || code > 111 {
+			return nil, fmt.Errorf("invalid Chaco code: %s", format[0])
+		}
+		layout.vertexNumbers = code/100 == 1
+		layout.vertexWeights = (code/10)%10 == 1
+		layout.edgeWeights = code%10 == 1
+	}
+
+	return lines.adjacency(nvtxs, layout)
+}
+
+// graphLineLayout describes the fields of a vertex line shared by the METIS
+// and Chaco graph formats
+type graphLineLayout struct {
+	vertexNumbers   bool // Line starts with its own 1-based vertex number
+	vertexWeights   bool // Followed by the vertex weight
+	edgeWeights     bool // Every neighbor id is followed by an edge weight
+	realEdgeWeights bool // Edge weights may be real and are rounded
+}
+
+// graphLineReader scans graph files line by line, skipping % comments
+type graphLineReader struct {
+	scanner   *bufio.Scanner
+	skipBlank bool // Skip blank lines as well, see vertexLineCount
+}
+
+func newGraphLineReader(r io.Reader) *graphLineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), math.MaxInt32)
+	return &graphLineReader{scanner: scanner}
+}
+
+// next advances to the next line that is not a comment, nor blank when
+// skipBlank is set. Comments may be indented.
+func (l *graphLineReader) next() bool {
+	for l.scanner.Scan() {
+		line := bytes.TrimSpace(l.scanner.Bytes())
+		if len(line) > 0 && line[0] == '%' {
+			continue
+		}
+		if len(line) == 0 && l.skipBlank {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// vertexLineCount tallies the lines following the header of a METIS graph
+// file to classify its blank lines. A blank line is normally the empty
+// adjacency list of an isolated vertex, which leaves blank lines inserted
+// between vertices, as some tools and editors do, indistinguishable from
+// vertices. They are taken for strays when there are more lines than the
+// nvtxs declared by the header but exactly nvtxs of them are not blank; in
+// every other case blank lines remain vertices and the vertices are the first
+// nvtxs lines, whatever follows them.
+//
+// Readers parse the non-blank lines as they are counted. A line that fails to
+// parse only matters if it turns out to be a vertex, so its error is held
+// back, parsing stops there and classify reports it if it applies.
+type vertexLineCount struct {
+	nvtxs     int
+	lines     int
+	nonBlank  int
+	blanks    []int // Positions of the blank lines among the first nvtxs
+	failedAt  int   // Position of the line that failed to parse, or -1
+	failedRow int   // Its position among the non-blank lines
+}
+
+func newVertexLineCount(nvtxs int) *vertexLineCount {
+	return &vertexLineCount{nvtxs: nvtxs, failedAt: -1, failedRow: -1}
+}
+
+// add counts line and returns its position among the non-blank lines, and
+// whether to parse it: it is not blank, may be a vertex and no line failed
+// before it
+func (c *vertexLineCount) add(line []byte) (row int, parse bool) {
+	row = c.nonBlank
+	if len(bytes.TrimSpace(line)) == 0 {
+		if c.lines < c.nvtxs {
+			c.blanks = append(c.blanks, c.lines)
+		}
+		c.lines++
+		return row, false
+	}
+	c.nonBlank++
+	c.lines++
+	return row, row < c.nvtxs && c.failedAt < 0
+}
+
+// fail records that the line just added, at row, failed to parse
+func (c *vertexLineCount) fail(row int) {
+	c.failedAt, c.failedRow = c.lines-1, row
+}
+
+// done reports whether further lines cannot change the classification: with
+// more than nvtxs non-blank lines, the vertices are the first nvtxs lines
+func (c *vertexLineCount) done() bool {
+	return c.nonBlank > c.nvtxs && c.lines >= c.nvtxs
+}
+
+// strays reports whether the blank lines counted so far are strays
+func (c *vertexLineCount) strays() bool {
+	return c.lines > c.nvtxs && c.nonBlank == c.nvtxs
+}
+
+// classify returns the first error among the lines found to be vertices once
+// every line is counted. failure returns the error of the line that failed to
+// parse, numbered as vertex i; blank returns that of a blank line taken for
+// vertex i, if any.
+func (c *vertexLineCount) classify(failure, blank func(i int) error) error {
+	if c.strays() {
+		if c.failedAt >= 0 {
+			return failure(c.failedRow)
+		}
+		return nil
+	}
+
+	if len(c.blanks) > 0 && (c.failedAt < 0 || c.blanks[0] < c.failedAt) {
+		if err := blank(c.blanks[0]); err != nil {
+			return err
+		}
+	}
+	if c.failedAt >= 0 && c.failedAt < c.nvtxs {
+		return failure(c.failedAt)
+	}
+	if c.lines < c.nvtxs {
+		return fmt.Errorf("unexpected EOF at vertex %d", c.lines)
+	}
+	return nil
+}
+
+// rows returns the number of non-blank lines that are vertices
+func (c *vertexLineCount) rows() int {
+	if c.strays() {
+		return c.nvtxs
+	}
+	return c.nvtxs - len(c.blanks)
+}
+
+// vertexXadj turns xadj, indexing the adjacency lists of the non-blank lines,
+// into the xadj of the vertices, with the blank lines spliced in as isolated
+// vertices unless they are strays
+func (c *vertexLineCount) vertexXadj(xadj []int32) []int32 {
+	if c.strays() {
+		return xadj[:c.nvtxs+1]
+	}
+	return spliceBlankVertices(xadj[:c.rows()+1], c.nvtxs, c.blanks)
+}
+
+// spliceBlankVertices turns xadj, indexing the adjacency lists of the
+// non-blank vertex lines, into the xadj of nvtxs vertices in which the lines
+// at the ascending positions blanks are isolated vertices. It works in place,
+// growing xadj to nvtxs+1 entries as needed.
+func spliceBlankVertices(xadj []int32, nvtxs int, blanks []int) []int32 {
+	for len(xadj) < nvtxs+1 {
+		xadj = append(xadj, 0)
+	}
+	xadj = xadj[:nvtxs+1]
+
+	// Walk backwards so that xadj[row] is read before it is overwritten: row
+	// counts the non-blank lines up to pos and never exceeds pos+1
+	row, b := nvtxs-len(blanks), len(blanks)-1
+	for pos := nvtxs - 1; pos >= 0; pos-- {
+		xadj[pos+1] = xadj[row]
+		if b >= 0 && blanks[b] == pos {
+			b--
+		} else {
+			row--
+		}
+	}
+	return xadj
+}
+
+// header reads the vertex count and returns the header fields after the edge
+// count, which is not needed to assemble the graph
+func (l *graphLineReader) header() (nvtxs int, format []string, err error) {
+	if !l.next() {
+		return 0, nil, fmt.Errorf("empty file")
+	}
+
+	header := strings.Fields(l.scanner.Text())
+	if len(header) < 2 {
+		return 0, nil, fmt.Errorf("invalid header: %s", l.scanner.Text())
+	}
+
+	nvtxs, err = strconv.Atoi(header[0])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid number of vertices: %v", err)
+	}
+	if nvtxs < 0 || nvtxs > math.MaxInt32-1 {
+		return 0, nil, fmt.Errorf("invalid number of vertices: %d", nvtxs)
+	}
+
+	return nvtxs, header[2:], nil
+}
+
+// adjacency reads nvtxs vertex lines laid out as described by layout and
+// assembles them into a CSR graph
+func (l *graphLineReader) adjacency(nvtxs int, layout graphLineLayout) (*Graph, error) {
+	rows := newVertexLines()
+	for i := 0; i < nvtxs; i++ {
+		if !l.next() {
+			return nil, fmt.Errorf("unexpected EOF at vertex %d", i)
+		}
+		if err := rows.parse(l.scanner.Text(), i, nvtxs, layout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	return rows.graph(nvtxs, layout), nil
+}
+
+// metisAdjacency reads the vertex lines of a METIS graph file like adjacency,
+// classifying blank lines with a vertexLineCount on the way. Non-blank lines
+// are parsed as they stream by and blank ones only counted, so once the
+// classification is known the graph only needs the blank lines spliced in or
+// dropped.
+func (l *graphLineReader) metisAdjacency(nvtxs int, layout graphLineLayout) (*Graph, error) {
+	rows := newVertexLines()
+	count := newVertexLineCount(nvtxs)
+	var failed string
+	for !count.done() && l.next() {
+		row, parse := count.add(l.scanner.Bytes())
+		if !parse {
+			continue
+		}
+		line := l.scanner.Text()
+		if err := rows.parse(line, row, nvtxs, layout); err != nil {
+			failed = line
+			count.fail(row)
+		}
+	}
+	if err := l.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	// Errors name the vertex by its classified number, so they come from
+	// parsing the line again under that number
+	err := count.classify(
+		func(i int) error { return newVertexLines().parse(failed, i, nvtxs, layout) },
+		func(i int) error { return newVertexLines().parse("", i, nvtxs, layout) })
+	if err != nil {
+		return nil, err
+	}
+
+	g := rows.graph(count.rows(), layout)
+	g.Xadj = count.vertexXadj(g.Xadj)
+	return g, nil
+}
+
+// vertexLines accumulates parsed vertex lines in CSR form
+type vertexLines struct {
+	xadj   []int32
+	adjncy []int32
+	vwgt   []int32
+	adjwgt []int32
+}
+
+func newVertexLines() *vertexLines {
+	// xadj grows as lines are read so that a bogus vertex count in the header
+	// cannot force a huge allocation
+	return &vertexLines{
+		xadj:   []int32{0},
+		adjncy: []int32{},
+		vwgt:   []int32{},
+		adjwgt: []int32{},
+	}
+}
+
+// parse appends line as vertex i of a graph of nvtxs vertices laid out as
+// described by layout
+func (a *vertexLines) parse(line string, i, nvtxs int, layout graphLineLayout) error {
+	fields := strings.Fields(line)
+	fieldIdx := 0
+
+	// Read vertex number if present
+	if layout.vertexNumbers {
+		if len(fields) == 0 {
+			return fmt.Errorf("missing vertex number at vertex %d", i)
+		}
+		if v, err := strconv.Atoi(fields[0]); err != nil || v != i+1 {
+			return fmt.Errorf("vertex line %d is numbered %s", i+1, fields[0])
+		}
+		fieldIdx++
+	}
+
+	// Read vertex weight if present
+	if layout.vertexWeights {
+		if len(fields) <= fieldIdx {
+			return fmt.Errorf("missing vertex weight at vertex %d", i)
+		}
+		w, err := strconv.ParseInt(fields[fieldIdx], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid vertex weight at vertex %d: %v", i, err)
+		}
+		a.vwgt = append(a.vwgt, int32(w))
+		fieldIdx++
+	}
+
+	if layout.edgeWeights && (len(fields)-fieldIdx)%2 != 0 {
+		return fmt.Errorf("edge without weight at vertex %d", i)
+	}
+
+	// Read adjacency list
+	for j := fieldIdx; j < len(fields); j++ {
+		if layout.edgeWeights && (j-fieldIdx)%2 == 1 {
+			// This is an edge weight
+			w, err := parseEdgeWeight(fields[j], layout.realEdgeWeights)
+			if err != nil {
+				return fmt.Errorf("invalid edge weight at vertex %d: %v", i, err)
+			}
+			a.adjwgt = append(a.adjwgt, w)
+		} else {
+			// This is a vertex
+			v, err := strconv.Atoi(fields[j])
+			if err != nil {
+				return fmt.Errorf("invalid vertex id at vertex %d: %v", i, err)
+			}
+			if v < 1 || v > nvtxs {
+				return fmt.Errorf("vertex id %d at vertex %d outside [1, %d]", v, i, nvtxs)
+			}
+			// Convert to 0-based indexing
+			a.adjncy = append(a.adjncy, int32(v-1))
+		}
+	}
+
+	a.xadj = append(a.xadj, int32(len(a.adjncy)))
+	return nil
+}
+
+// graph assembles the first n vertices parsed into a CSR graph
+func (a *vertexLines) graph(n int, layout graphLineLayout) *Graph {
+	nedges := a.xadj[n]
+	g := &Graph{
+		Xadj:   a.xadj[:n+1],
+		Adjncy: a.adjncy[:nedges],
+	}
+
+	if layout.vertexWeights {
+		g.Vwgt = a.vwgt[:n]
+	}
+	if layout.edgeWeights {
+		g.Adjwgt = a.adjwgt[:nedges]
+	}
+
+	return g
+}
+
+// parseEdgeWeight parses an integer edge weight, or with allowReal set a real
+// one rounded to the nearest integer
+func parseEdgeWeight(field string, allowReal bool) (int32, error) {
+	if !allowReal {
+		w, err := strconv.ParseInt(field, 10, 32)
+		return int32(w), err
+	}
+
+	w, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, err
+	}
+	w = math.Round(w)
+	if math.IsNaN(w) || w < math.MinInt32 || w > math.MaxInt32 {
+		return 0, fmt.Errorf("weight %s out of range", field)
+	}
+	return int32(w), nil
+}
+
+// WriteGraphFile writes g in the METIS graph format read by ReadGraphFile.
+// Vertex ids are written 1-based, and the fmt field of the header records
+// which of Vwgt and Adjwgt are present.
+func WriteGraphFile(w io.Writer, g *Graph) error {
+	nvtxs := g.NumVertices()
+	if nvtxs < 0 {
+		nvtxs = 0
+	}
+
+	out := bufio.NewWriter(w)
+	format := 0
+	if g.Vwgt != nil {
+		format += 10
+	}
+	if g.Adjwgt != nil {
+		format++
+	}
+	if format != 0 {
+		fmt.Fprintf(out, "%d %d %d\n", nvtxs, g.NumEdges(), format)
+	} else {
+		fmt.Fprintf(out, "%d %d\n", nvtxs, g.NumEdges())
+	}
+
+	var buf []byte
+	for i := 0; i < nvtxs; i++ {
+		buf = buf[:0]
+		if g.Vwgt != nil {
+			buf = strconv.AppendInt(buf, int64(g.Vwgt[i]), 10)
+		}
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			if len(buf) > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = strconv.AppendInt(buf, int64(g.Adjncy[j])+1, 10)
+			if g.Adjwgt != nil {
+				buf = append(buf, ' ')
+				buf = strconv.AppendInt(buf, int64(g.Adjwgt[j]), 10)
+			}
+		}
+		buf = append(buf, '\n')
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// WritePartitioning writes partition information to a writer
+func WritePartitioning(w io.Writer, part []int32) error {
+	return WritePartitioningBase(w, part, 0)
+}
+
+// WritePartitioningBase writes one partition id per line like
+// WritePartitioning, numbering the partitions from base: 0 for the package's
+// own C-style ids, 1 for Fortran codes that expect ids 1..nparts.
+func WritePartitioningBase(w io.Writer, part []int32, base int32) error {
+	for _, p := range part {
+		if _, err := fmt.Fprintf(w, "%d\n", p+base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShiftBase adds delta to every entry of arr in place, converting index arrays
+// between numbering conventions: ShiftBase(part, 1) turns the 0-based arrays
+// returned by this package into the 1-based ones Fortran expects, and
+// ShiftBase(arr, -1) converts back. Everything the package accepts and returns
+// is 0-based.
+func ShiftBase(arr []int32, delta int32) {
+	for i := range arr {
+		arr[i] += delta
+	}
+}
+
+// ApplyPartitionStream reads one vertex id per line from r, looks up its
+// partition in part and writes transform(vertexID, part[vertexID]) followed by
+// a newline to w. Blank lines are skipped. Input is processed line by line and
+// output is buffered, so arbitrarily long vertex files can be annotated
+// without loading them into memory.
+func ApplyPartitionStream(r io.Reader, part []int32, w io.Writer, transform func(vertexID int32, p int32) string) error {
+	scanner := bufio.NewScanner(r)
+	out := bufio.NewWriter(w)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		field := bytes.TrimSpace(scanner.Bytes())
+		if len(field) == 0 {
+			continue
+		}
+
+		v, ok := parseVertexID(field)
+		if !ok {
+			return fmt.Errorf("invalid vertex id %q at line %d", field, line)
+		}
+		if int(v) >= len(part) {
+			return fmt.Errorf("vertex %d at line %d out of range [0, %d)", v, line, len(part))
+		}
+
+		if _, err := out.WriteString(transform(v, part[v])); err != nil {
+			return err
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading vertex ids: %v", err)
+	}
+
+	return out.Flush()
+}
+
+// parseVertexID parses a non-negative decimal id without allocating
+func parseVertexID(b []byte) (int32, bool) {
+	v := int64(0)
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + int64(c-'0')
+		if v > math.MaxInt32 {
+			return 0, false
+		}
+	}
+	return int32(v), true
+}
+
+// CalculateEdgeCut calculates the edge cut for a given partitioning
+func CalculateEdgeCut(g *Graph, part []int32) int32 {
+	edgeCut := int32(0)
+	g.EdgeIterator(func(u, v, weight int32) {
+		if part[u] != part[v] {
+			edgeCut += weight
+		}
+	})
+	return edgeCut
+}
+
+// MultiConstraintWeights returns the nparts by ncon matrix of the weight each
+// partition received under each constraint, to check that a multi-constraint
+// partition balanced every constraint and not just their sum. vwgt holds ncon
+// weights per vertex, vertex by vertex, as METIS lays them out. It returns nil
+// if ncon < 1 or vwgt does not hold len(part)*ncon weights.
+func MultiConstraintWeights(part []int32, vwgt []int32, ncon, nparts int32) [][]int64 {
+	if ncon < 1 || len(vwgt) != len(part)*int(ncon) {
+		return nil
+	}
+
+	weights := make([][]int64, nparts)
+	for p := range weights {
+		weights[p] = make([]int64, ncon)
+	}
+	for v, p := range part {
+		for c := int32(0); c < ncon; c++ {
+			weights[p][c] += int64(vwgt[int32(v)*ncon+c])
+		}
+	}
+	return weights
+}
+
+// CalculatePartitionBalance calculates partition balance statistics
+func CalculatePartitionBalance(part []int32, vwgt []int32, nparts int32) (min, max, avg float64) {
+	partWeights := make([]int64, nparts)
+	nvtxs := len(part)
+
+	for i := 0; i < nvtxs; i++ {
+		weight := int64(1)
+		if vwgt != nil && i < len(vwgt) {
+			weight = int64(vwgt[i])
+		}
+		partWeights[part[i]] += weight
+	}
+
+	totalWeight := int64(0)
+	minWeight := partWeights[0]
+	maxWeight := partWeights[0]
+
+	for _, w := range partWeights {
+		totalWeight += w
+		if w < minWeight {
+			minWeight = w
+		}
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+
+	avgWeight := float64(totalWeight) / float64(nparts)
+
+	return float64(minWeight), float64(maxWeight), avgWeight
+}
+
+// ConnectedComponents labels every vertex with the id of its connected
+// component. Components are numbered in order of their lowest vertex.
+func (g *Graph) ConnectedComponents() (comp []int32, ncomp int32) {
+	nvtxs := g.NumVertices()
+	if nvtxs <= 0 {
+		return nil, 0
+	}
+
+	comp = make([]int32, nvtxs)
+	for i := range comp {
+		comp[i] = -1
+	}
+
+	queue := make([]int32, 0, nvtxs)
+	for s := 0; s < nvtxs; s++ {
+		if comp[s] >= 0 {
+			continue
+		}
+		comp[s] = ncomp
+		queue = append(queue[:0], int32(s))
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, u := range g.Neighbors(int(v)) {
+				if comp[u] < 0 {
+					comp[u] = ncomp
+					queue = append(queue, u)
+				}
+			}
+		}
+		ncomp++
+	}
+
+	return comp, ncomp
+}
+
+// ErrDisconnected is wrapped by the error of WeaklyConnectedCheck
+var ErrDisconnected = errors.New("graph is disconnected")
+
+// WeaklyConnectedCheck returns nil if every vertex of g can be reached from
+// vertex 0, and otherwise an error wrapping ErrDisconnected that names the
+// first vertex left unreached. It costs a single breadth-first search, cheaper
+// than labeling all components with ConnectedComponents. METIS accepts
+// disconnected graphs but may scatter a component over several partitions or
+// pack unrelated components together; PartitionByComponents handles them
+// explicitly. A graph without vertices is connected.
+func (g *Graph) WeaklyConnectedCheck() error {
+	nvtxs := g.NumVertices()
+	if nvtxs <= 0 {
+		return nil
+	}
+
+	seen := make([]bool, nvtxs)
+	seen[0] = true
+	reached := 1
+	queue := []int32{0}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, u := range g.Neighbors(int(v)) {
+			if !seen[u] {
+				seen[u] = true
+				reached++
+				queue = append(queue, u)
+			}
+		}
+	}
+	if reached == nvtxs {
+		return nil
+	}
+
+	for v := range seen {
+		if !seen[v] {
+			return fmt.Errorf("%w: vertex %d is not reachable from vertex 0 (%d of %d vertices reached); consider PartitionByComponents",
+				ErrDisconnected, v, reached, nvtxs)
+		}
+	}
+	return nil
+}
+
+// IsolatedVertices returns, in increasing order, the vertices of g without
+// any neighbor. METIS places isolated vertices wherever they best fix the
+// balance, with no regard to locality, so they often land in partitions far
+// from the data they belong with; they also frequently point at missing edges
+// in the input.
+func (g *Graph) IsolatedVertices() []int32 {
+	var isolated []int32
+	for v := 0; v < g.NumVertices(); v++ {
+		if g.Xadj[v+1] == g.Xadj[v] {
+			isolated = append(isolated, int32(v))
+		}
+	}
+	return isolated
+}
+
+// ConnectIsolated returns a copy of g in which every isolated vertex is joined
+// by an edge to an anchor, so partitioning keeps it with that anchor instead
+// of placing it arbitrarily. With to >= 0 every isolated vertex is attached to
+// vertex to; with to < 0 each is attached to the non-isolated vertex nearest
+// to it by id, preferring the lower id on ties, or to vertex 0 when g has no
+// edges at all. New edges get unit weight when g.Adjwgt is set. The result is
+// connected if the non-isolated part of g is. ConnectIsolated returns nil if
+// to is not a vertex of g.
+func (g *Graph) ConnectIsolated(to int32) *Graph {
+	nvtxs := g.NumVertices()
+	if nvtxs < 0 || int(to) >= nvtxs {
+		return nil
+	}
+
+	var connected []int32
+	for v := 0; v < nvtxs; v++ {
+		if g.Xadj[v+1] > g.Xadj[v] {
+			connected = append(connected, int32(v))
+		}
+	}
+	anchorOf := func(v int32) int32 {
+		if to >= 0 {
+			return to
+		}
+		if len(connected) == 0 {
+			return 0
+		}
+		k := sort.Search(len(connected), func(i int) bool { return connected[i] > v })
+		if k == len(connected) || k > 0 && v-connected[k-1] <= connected[k]-v {
+			return connected[k-1]
+		}
+		return connected[k]
+	}
+
+	// extra[v] lists the vertices newly joined to v
+	extra := make(map[int32][]int32)
+	added := 0
+	for _, v := range g.IsolatedVertices() {
+		a := anchorOf(v)
+		if a == v {
+			continue
+		}
+		extra[v] = append(extra[v], a)
+		extra[a] = append(extra[a], v)
+		added += 2
+	}
+
+	out := &Graph{Xadj: make([]int32, nvtxs+1), Adjncy: make([]int32, 0, len(g.Adjncy)+added)}
+	if g.Vwgt != nil {
+		out.Vwgt = append([]int32(nil), g.Vwgt...)
+	}
+	if g.Labels != nil {
+		out.Labels = append([]string(nil), g.Labels...)
+	}
+	if g.Adjwgt != nil {
+		out.Adjwgt = make([]int32, 0, cap(out.Adjncy))
+	}
+	for v := 0; v < nvtxs; v++ {
+		out.Adjncy = append(out.Adjncy, g.Neighbors(v)...)
+		out.Adjncy = append(out.Adjncy, extra[int32(v)]...)
+		if g.Adjwgt != nil {
+			out.Adjwgt = append(out.Adjwgt, g.Adjwgt[g.Xadj[v]:g.Xadj[v+1]]...)
+			for range extra[int32(v)] {
+				out.Adjwgt = append(out.Adjwgt, 1)
+			}
+		}
+		out.Xadj[v+1] = int32(len(out.Adjncy))
+	}
+
+	return out
+}
+
+// RelabelBFS renumbers the vertices of g in breadth-first order from start, so
+// that neighbors get nearby ids and CSR traversals touch memory more locally.
+// Vertices unreachable from start are visited by further searches from the
+// lowest unvisited vertex. It returns the relabeled graph, with weights and
+// labels carried over, and perm, where perm[k] is the original vertex given
+// new id k, the same convention as the perm array of NodeND. RelabelBFS
+// returns nil, nil if start is not a vertex of g.
+func (g *Graph) RelabelBFS(start int32) (relabeled *Graph, perm []int32) {
+	nvtxs := g.NumVertices()
+	if start < 0 || int(start) >= nvtxs {
+		return nil, nil
+	}
+
+	iperm := make([]int32, nvtxs)
+	for i := range iperm {
+		iperm[i] = -1
+	}
+	perm = make([]int32, 0, nvtxs)
+
+	next := 0
+	for root := int(start); len(perm) < nvtxs; root = next {
+		iperm[root] = int32(len(perm))
+		perm = append(perm, int32(root))
+
+		// perm doubles as the BFS queue
+		for head := len(perm) - 1; head < len(perm); head++ {
+			for _, u := range g.Neighbors(int(perm[head])) {
+				if iperm[u] < 0 {
+					iperm[u] = int32(len(perm))
+					perm = append(perm, u)
+				}
+			}
+		}
+
+		for next < nvtxs && iperm[next] >= 0 {
+			next++
+		}
+	}
+
+	relabeled = &Graph{
+		Xadj:   make([]int32, nvtxs+1),
+		Adjncy: make([]int32, 0, len(g.Adjncy)),
+	}
+	if g.Vwgt != nil {
+		relabeled.Vwgt = make([]int32, nvtxs)
+	}
+	if g.Adjwgt != nil {
+		relabeled.Adjwgt = make([]int32, 0, len(g.Adjwgt))
+	}
+	if g.Labels != nil {
+		relabeled.Labels = make([]string, nvtxs)
+	}
+
+	for k, v := range perm {
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			relabeled.Adjncy = append(relabeled.Adjncy, iperm[g.Adjncy[j]])
+			if g.Adjwgt != nil {
+				relabeled.Adjwgt = append(relabeled.Adjwgt, g.Adjwgt[j])
+			}
+		}
+		relabeled.Xadj[k+1] = int32(len(relabeled.Adjncy))
+		if g.Vwgt != nil {
+			relabeled.Vwgt[k] = g.Vwgt[v]
+		}
+		if g.Labels != nil {
+			relabeled.Labels[k] = g.Label(int(v))
+		}
+	}
+
+	return relabeled, perm
+}
+
+// SubsetCut returns the total weight of the edges joining the vertices in
+// subset to the rest of the graph, the cut a partition consisting of exactly
+// those vertices would have. Unit weights are used when Adjwgt is nil, and
+// repeated vertices in subset count once.
+func (g *Graph) SubsetCut(subset []int32) int32 {
+	inside := make([]bool, g.NumVertices())
+	for _, v := range subset {
+		inside[v] = true
+	}
+
+	cut := int32(0)
+	for v, in := range inside {
+		if !in {
+			continue
+		}
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			if inside[g.Adjncy[j]] {
+				continue
+			}
+			if g.Adjwgt != nil {
+				cut += g.Adjwgt[j]
+			} else {
+				cut++
+			}
+		}
+	}
+
+	return cut
+}
+
+// Subgraph returns the subgraph induced by the given vertices. Vertex i of the
+// subgraph corresponds to vertex vertices[i] of g, and vertex and edge weights
+// and labels are carried over when present.
+func (g *Graph) Subgraph(vertices []int32) *Graph {
+	local := make(map[int32]int32, len(vertices))
+	for i, v := range vertices {
+		local[v] = int32(i)
+	}
+
+	sub := &Graph{
+		Xadj:   make([]int32, len(vertices)+1),
+		Adjncy: []int32{},
+	}
+	if g.Vwgt != nil {
+		sub.Vwgt = make([]int32, len(vertices))
+	}
+	if g.Adjwgt != nil {
+		sub.Adjwgt = []int32{}
+	}
+	if g.Labels != nil {
+		sub.Labels = make([]string, len(vertices))
+	}
+
+	for i, v := range vertices {
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			u, ok := local[g.Adjncy[j]]
+			if !ok {
+				continue
+			}
+			sub.Adjncy = append(sub.Adjncy, u)
+			if g.Adjwgt != nil {
+				sub.Adjwgt = append(sub.Adjwgt, g.Adjwgt[j])
+			}
+		}
+		sub.Xadj[i+1] = int32(len(sub.Adjncy))
+		if g.Vwgt != nil {
+			sub.Vwgt[i] = g.Vwgt[v]
+		}
+		if g.Labels != nil {
+			sub.Labels[i] = g.Label(int(v))
+		}
+	}
+
+	return sub
 }
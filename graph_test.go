@@ -0,0 +1,958 @@
+package metis
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubgraph(t *testing.T) {
+	// Path 0-1-2-3 with edge weights 1, 2, 3
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 5, 6},
+		Adjncy: []int32{1, 0, 2, 1, 3, 2},
+		Vwgt:   []int32{10, 20, 30, 40},
+		Adjwgt: []int32{1, 1, 2, 2, 3, 3},
+	}
+
+	sub := g.Subgraph([]int32{1, 2, 3})
+	assert.Equal(t, 3, sub.NumVertices())
+	assert.Equal(t, []int32{0, 1, 3, 4}, sub.Xadj)
+	assert.Equal(t, []int32{1, 0, 2, 1}, sub.Adjncy)
+	assert.Equal(t, []int32{20, 30, 40}, sub.Vwgt)
+	assert.Equal(t, []int32{2, 2, 3, 3}, sub.Adjwgt)
+
+	// Unweighted graphs stay unweighted
+	sub = NewGraph(g.Xadj, g.Adjncy).Subgraph([]int32{0, 3})
+	assert.Equal(t, []int32{0, 0, 0}, sub.Xadj)
+	assert.Nil(t, sub.Vwgt)
+	assert.Nil(t, sub.Adjwgt)
+}
+
+func TestGeometricEdgeWeights(t *testing.T) {
+	// Triangle with edge lengths 1 (0-1), 2 (0-2) and sqrt(5) (1-2)
+	g := NewGraph([]int32{0, 2, 4, 6}, []int32{1, 2, 0, 2, 0, 1})
+	coords := [][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}}
+
+	inverse := func(d float64) int32 { return int32(100 / d) }
+	adjwgt := GeometricEdgeWeights(g, coords, inverse)
+	assert.Equal(t, []int32{100, 50, 100, 44, 50, 44}, adjwgt)
+}
+
+func TestApplyPartitionStream(t *testing.T) {
+	part := []int32{2, 0, 1, 1}
+	label := func(v, p int32) string { return fmt.Sprintf("%d part=%d", v, p) }
+
+	var out strings.Builder
+	err := ApplyPartitionStream(strings.NewReader("3\n0\n\n 2 \n"), part, &out, label)
+	require.NoError(t, err)
+	assert.Equal(t, "3 part=1\n0 part=2\n2 part=1\n", out.String())
+
+	// Out of range and malformed ids are reported with their line
+	err = ApplyPartitionStream(strings.NewReader("1\n4\n"), part, &out, label)
+	assert.ErrorContains(t, err, "line 2")
+	err = ApplyPartitionStream(strings.NewReader("x\n"), part, &out, label)
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestCheckWeights(t *testing.T) {
+	// Path 0-1-2 with a zero vertex weight and a negative edge weight
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 4},
+		Adjncy: []int32{1, 0, 2, 1},
+		Vwgt:   []int32{1, 0, 2},
+		Adjwgt: []int32{1, 1, -3, -3},
+	}
+
+	warnings := g.CheckWeights()
+	require.Len(t, warnings, 3)
+
+	assert.Equal(t, WeightWarning{Vertex: 1, Edge: -1, Neighbor: -1, Weight: 0}, warnings[0])
+	assert.Equal(t, WeightWarning{Vertex: 1, Edge: 2, Neighbor: 2, Weight: -3, IsError: true}, warnings[1])
+	assert.Equal(t, WeightWarning{Vertex: 2, Edge: 3, Neighbor: 1, Weight: -3, IsError: true}, warnings[2])
+	assert.Equal(t, "error: edge 2 (1-2) has weight -3", warnings[1].String())
+
+	// Unweighted graphs have nothing to report
+	assert.Empty(t, NewGraph(g.Xadj, g.Adjncy).CheckWeights())
+}
+
+func TestLaplacianCSR(t *testing.T) {
+	// Weighted path 0 -(2)- 1 -(3)- 2
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 4},
+		Adjncy: []int32{1, 0, 2, 1},
+		Adjwgt: []int32{2, 2, 3, 3},
+	}
+
+	xadj, adjncy, values := g.LaplacianCSR()
+	assert.Equal(t, []int32{0, 2, 5, 7}, xadj)
+	assert.Equal(t, []int32{0, 1, 1, 0, 2, 2, 1}, adjncy)
+	assert.Equal(t, []float64{2, -2, 5, -2, -3, 3, -3}, values)
+
+	// Rows of a Laplacian sum to zero
+	for i := 0; i < 3; i++ {
+		sum := 0.0
+		for j := xadj[i]; j < xadj[i+1]; j++ {
+			sum += values[j]
+		}
+		assert.Zero(t, sum)
+	}
+}
+
+func TestFilterEdges(t *testing.T) {
+	// Square 0-1-2-3-0 with one weak side (1-2) and a one-sided weak
+	// direction on 3-0
+	g := &Graph{
+		Xadj:   []int32{0, 2, 4, 6, 8},
+		Adjncy: []int32{1, 3, 0, 2, 1, 3, 0, 2},
+		Vwgt:   []int32{1, 2, 3, 4},
+		Adjwgt: []int32{5, 5, 5, 1, 1, 5, 2, 5},
+	}
+
+	f := g.FilterEdges(3)
+	assert.Equal(t, []int32{0, 1, 2, 3, 4}, f.Xadj)
+	assert.Equal(t, []int32{1, 0, 3, 2}, f.Adjncy)
+	assert.Equal(t, []int32{5, 5, 5, 5}, f.Adjwgt)
+	assert.Equal(t, g.Vwgt, f.Vwgt)
+
+	// Symmetry is preserved: every remaining edge has its reverse
+	for v := 0; v < f.NumVertices(); v++ {
+		for _, u := range f.Neighbors(v) {
+			assert.Contains(t, f.Neighbors(int(u)), int32(v))
+		}
+	}
+
+	// Vertices that lose every edge are kept
+	f = g.FilterEdges(10)
+	assert.Equal(t, 4, f.NumVertices())
+	assert.Empty(t, f.Adjncy)
+
+	// The input graph is not modified
+	assert.Len(t, g.Adjncy, 8)
+}
+
+func TestMatrixExport(t *testing.T) {
+	// Weighted path 0 -(2)- 1 -(3)- 2
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 4},
+		Adjncy: []int32{1, 0, 2, 1},
+		Adjwgt: []int32{2, 2, 3, 3},
+	}
+
+	assert.Equal(t, [][]int32{
+		{0, 2, 0},
+		{2, 0, 3},
+		{0, 3, 0},
+	}, g.ToDenseMatrix())
+
+	rows, cols, vals := g.ToTriplets()
+	assert.Equal(t, []int32{0, 1, 1, 2}, rows)
+	assert.Equal(t, []int32{1, 0, 2, 1}, cols)
+	assert.Equal(t, []int32{2, 2, 3, 3}, vals)
+
+	// Unweighted graphs use unit values
+	_, _, vals = NewGraph(g.Xadj, g.Adjncy).ToTriplets()
+	assert.Equal(t, []int32{1, 1, 1, 1}, vals)
+
+	// Dense export refuses huge graphs
+	big := NewGraph(make([]int32, MaxDenseVertices+2), nil)
+	assert.Nil(t, big.ToDenseMatrix())
+}
+
+func TestDegreeMetrics(t *testing.T) {
+	t.Run("Grid", func(t *testing.T) {
+		g := NewGraph(createGridGraph(5, 5))
+
+		deg := g.DegreeSequence()
+		require.Len(t, deg, 25)
+		assert.Equal(t, int32(2), deg[0])
+		assert.Equal(t, int32(3), deg[1])
+		assert.Equal(t, int32(4), deg[6])
+
+		assert.Greater(t, g.Assortativity(), 0.0)
+	})
+
+	t.Run("Star", func(t *testing.T) {
+		// Vertex 0 is the hub of four leaves
+		g := NewGraph([]int32{0, 4, 5, 6, 7, 8}, []int32{1, 2, 3, 4, 0, 0, 0, 0})
+
+		assert.Equal(t, []int32{4, 1, 1, 1, 1}, g.DegreeSequence())
+		assert.InDelta(t, -1.0, g.Assortativity(), 1e-12)
+	})
+
+	t.Run("Undefined", func(t *testing.T) {
+		// A cycle is regular and a graph without edges has no pairs
+		ring := NewGraph([]int32{0, 2, 4, 6}, []int32{1, 2, 0, 2, 0, 1})
+		assert.True(t, math.IsNaN(ring.Assortativity()))
+		assert.True(t, math.IsNaN(NewGraph([]int32{0, 0, 0}, nil).Assortativity()))
+	})
+}
+
+func TestConnectedComponents(t *testing.T) {
+	// Path 0-1, isolated 2, path 3-4
+	g := NewGraph([]int32{0, 1, 2, 2, 3, 4}, []int32{1, 0, 4, 3})
+	comp, ncomp := g.ConnectedComponents()
+	assert.Equal(t, int32(3), ncomp)
+	assert.Equal(t, []int32{0, 0, 1, 2, 2}, comp)
+
+	comp, ncomp = NewGraph(createGridGraph(3, 3)).ConnectedComponents()
+	assert.Equal(t, int32(1), ncomp)
+	assert.Len(t, comp, 9)
+}
+
+func TestWeaklyConnectedCheck(t *testing.T) {
+	assert.NoError(t, NewGraph(createGridGraph(3, 3)).WeaklyConnectedCheck())
+	assert.NoError(t, NewGraph([]int32{0}, nil).WeaklyConnectedCheck())
+
+	// Path 0-1, isolated 2, path 3-4
+	err := NewGraph([]int32{0, 1, 2, 2, 3, 4}, []int32{1, 0, 4, 3}).WeaklyConnectedCheck()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrDisconnected)
+	assert.Contains(t, err.Error(), "vertex 2")
+	assert.Contains(t, err.Error(), "PartitionByComponents")
+}
+
+// randomGraph builds a symmetric graph without self-loops or duplicate edges.
+// flags bit 0 adds vertex weights and bit 1 edge weights.
+func randomGraph(seed int64, nvtxs int, density int, flags uint8) *Graph {
+	rng := rand.New(rand.NewSource(seed))
+
+	adj := make([]map[int32]int32, nvtxs)
+	for i := range adj {
+		adj[i] = map[int32]int32{}
+	}
+	for i := 0; i < nvtxs; i++ {
+		for k := 0; k < density; k++ {
+			j := rng.Intn(nvtxs)
+			if j == i {
+				continue
+			}
+			w := int32(1 + rng.Intn(1000))
+			if _, ok := adj[i][int32(j)]; !ok {
+				adj[i][int32(j)] = w
+				adj[j][int32(i)] = w
+			}
+		}
+	}
+
+	g := &Graph{Xadj: make([]int32, nvtxs+1), Adjncy: []int32{}}
+	if flags&1 != 0 {
+		g.Vwgt = make([]int32, nvtxs)
+	}
+	if flags&2 != 0 {
+		g.Adjwgt = []int32{}
+	}
+	for i := 0; i < nvtxs; i++ {
+		nbrs := make([]int32, 0, len(adj[i]))
+		for v := range adj[i] {
+			nbrs = append(nbrs, v)
+		}
+		sort.Slice(nbrs, func(a, b int) bool { return nbrs[a] < nbrs[b] })
+		for _, v := range nbrs {
+			g.Adjncy = append(g.Adjncy, v)
+			if g.Adjwgt != nil {
+				g.Adjwgt = append(g.Adjwgt, adj[i][v])
+			}
+		}
+		g.Xadj[i+1] = int32(len(g.Adjncy))
+		if g.Vwgt != nil {
+			g.Vwgt[i] = int32(rng.Intn(100))
+		}
+	}
+
+	return g
+}
+
+func TestGraphFile(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		g := randomGraph(1, 20, 3, 3)
+		var buf bytes.Buffer
+		require.NoError(t, WriteGraphFile(&buf, g))
+
+		read, err := ReadGraphFile(&buf)
+		require.NoError(t, err)
+		assert.True(t, g.Equal(read))
+	})
+
+	t.Run("IsolatedVertex", func(t *testing.T) {
+		// Vertex 2 has no neighbors and its line is empty
+		g, err := ReadGraphFile(strings.NewReader("3 1\n2\n1\n\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 2}, g.Xadj)
+		assert.Equal(t, []int32{1, 0}, g.Adjncy)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		g, err := ReadGraphFile(strings.NewReader("% a path\n2 1 11\n% vertex 1\n4 2 7\n5 1 7\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{4, 5}, g.Vwgt)
+		assert.Equal(t, []int32{7, 7}, g.Adjwgt)
+	})
+
+	t.Run("StrayBlankLines", func(t *testing.T) {
+		// A path 0-1-2 with blank lines between and after the vertices
+		for _, input := range []string{
+			"3 2\n2\n\n1 3\n\n2\n\n",
+			"3 2\n2\n1 3\n2\n\n\n",
+			"  % indented comment\n3 2\n2\n \t\n1 3\n\t% comment\n2\n",
+		} {
+			g, err := ReadGraphFile(strings.NewReader(input))
+			require.NoError(t, err, input)
+			assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj, input)
+			assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy, input)
+		}
+
+		g, err := ReadGraphFile(strings.NewReader("2 1 10\n\n4 2\n\n5 1\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{4, 5}, g.Vwgt)
+
+		// With a blank line per missing vertex, blank lines stay isolated vertices
+		g, err = ReadGraphFile(strings.NewReader("4 1\n2\n1\n\n\n\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 2, 2}, g.Xadj)
+
+		// Lines past the vertices are not parsed
+		g, err = ReadGraphFile(strings.NewReader("3 1\n2\n1\n\nx\ny\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 2}, g.Xadj)
+
+		// An interior blank line and a trailing non-blank line: with exactly
+		// nvtxs non-blank lines the blank line is a stray and the trailing line
+		// a vertex, with more it is a vertex and the trailing lines are ignored
+		g, err = ReadGraphFile(strings.NewReader("3 1\n2\n\n1 3\n2\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj)
+		assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy)
+		g, err = ReadGraphFile(strings.NewReader("2 1\n2\n\n1\nx\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 1}, g.Xadj)
+		assert.Equal(t, []int32{1}, g.Adjncy)
+	})
+
+	for name, input := range map[string]string{
+		"Ncon":           "2 1 10 2\n1 1 2\n1 1 1\n",
+		"VertexSizes":    "2 1 100\n1 2\n1 1\n",
+		"OutOfRange":     "2 1\n3\n1\n",
+		"MissingWeight":  "2 1 1\n2\n1 1\n",
+		"NegativeHeader": "-1 0\n",
+		"Truncated":      "3 1\n2\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadGraphFile(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGraphEqual(t *testing.T) {
+	g := NewGraph(createGridGraph(3, 3))
+	h := NewGraph(createGridGraph(3, 3))
+	assert.True(t, g.Equal(h))
+
+	h.Vwgt = make([]int32, 9)
+	assert.False(t, g.Equal(h))
+	h.Vwgt = nil
+	h.Adjncy[0] = 2
+	assert.False(t, g.Equal(h))
+
+	var none *Graph
+	assert.False(t, g.Equal(none))
+	assert.True(t, none.Equal(nil))
+}
+
+// writeTempGraph writes contents to a file in a test temporary directory
+func writeTempGraph(tb testing.TB, contents []byte) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "graph.txt")
+	if err := os.WriteFile(path, contents, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestReadGraphFileCSR(t *testing.T) {
+	for flags := uint8(0); flags < 4; flags++ {
+		g := randomGraph(int64(flags), 200, 5, flags)
+		var buf bytes.Buffer
+		require.NoError(t, WriteGraphFile(&buf, g))
+
+		read, err := ReadGraphFileCSR(writeTempGraph(t, buf.Bytes()))
+		require.NoError(t, err)
+		assert.True(t, g.Equal(read), "flags %d", flags)
+
+		// Arrays are sized exactly
+		assert.Equal(t, len(read.Adjncy), cap(read.Adjncy))
+	}
+
+	t.Run("CommentsAndIsolated", func(t *testing.T) {
+		g, err := ReadGraphFileCSR(writeTempGraph(t, []byte("% c\n3 1 10\n1 2\n% c\n-2\t1\r\n4\n")))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 2}, g.Xadj)
+		assert.Equal(t, []int32{1, 0}, g.Adjncy)
+		assert.Equal(t, []int32{1, -2, 4}, g.Vwgt)
+	})
+
+	t.Run("StrayBlankLines", func(t *testing.T) {
+		g, err := ReadGraphFileCSR(writeTempGraph(t, []byte("% c\n3 2 10\n\n1 2\n\n  % c\n2 1 3\n\n3 2\n\n")))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj)
+		assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy)
+		assert.Equal(t, []int32{1, 2, 3}, g.Vwgt)
+
+		g, err = ReadGraphFileCSR(writeTempGraph(t, []byte("3 1\n2\n1\n\nx\ny\n")))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 2}, g.Xadj)
+		assert.Equal(t, []int32{1, 0}, g.Adjncy)
+
+		// Blank lines are classified as by ReadGraphFile
+		g, err = ReadGraphFileCSR(writeTempGraph(t, []byte("3 1\n2\n\n1 3\n2\n")))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj)
+		assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy)
+		g, err = ReadGraphFileCSR(writeTempGraph(t, []byte("2 1\n2\n\n1\nx\n")))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 1}, g.Xadj)
+		assert.Equal(t, []int32{1}, g.Adjncy)
+	})
+
+	for name, input := range map[string]string{
+		"OutOfRange":    "2 1\n3\n1\n",
+		"MissingWeight": "2 1 1\n2\n1 1\n",
+		"EmptyWeighted": "2 0 10\n\n1\n",
+		"BadNumber":     "2 1\n2x\n1\n",
+		"Truncated":     "3 1\n2\n",
+		"HugeHeader":    "1000000 0\n",
+		"Ncon":          "2 1 10 2\n1 1 2\n1 1 1\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadGraphFileCSR(writeTempGraph(t, []byte(input)))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := ReadGraphFileCSR(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+// benchmarkGraphFile is a weighted 300x300 grid in METIS format
+func benchmarkGraphFile(b *testing.B) []byte {
+	g := NewGraph(createGridGraph(300, 300))
+	g.Vwgt = make([]int32, g.NumVertices())
+	g.Adjwgt = make([]int32, len(g.Adjncy))
+	for i := range g.Vwgt {
+		g.Vwgt[i] = 1
+	}
+	for i := range g.Adjwgt {
+		g.Adjwgt[i] = 1
+	}
+	var buf bytes.Buffer
+	if err := WriteGraphFile(&buf, g); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// The allocated bytes per operation bound the peak heap use of each reader
+func BenchmarkReadGraphFile(b *testing.B) {
+	path := writeTempGraph(b, benchmarkGraphFile(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ReadGraphFile(f); err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+func BenchmarkReadGraphFileCSR(b *testing.B) {
+	path := writeTempGraph(b, benchmarkGraphFile(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadGraphFileCSR(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func FuzzGraphRoundTrip(f *testing.F) {
+	f.Add(int64(0), uint8(1), uint8(0), uint8(0))
+	f.Add(int64(1), uint8(10), uint8(2), uint8(1))
+	f.Add(int64(2), uint8(50), uint8(4), uint8(2))
+	f.Add(int64(3), uint8(100), uint8(8), uint8(3))
+	f.Add(int64(4), uint8(255), uint8(1), uint8(3))
+
+	f.Fuzz(func(t *testing.T, seed int64, nvtxs, density, flags uint8) {
+		if nvtxs == 0 {
+			return
+		}
+		g := randomGraph(seed, int(nvtxs), int(density%16), flags)
+
+		var buf bytes.Buffer
+		if err := WriteGraphFile(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		text := buf.String()
+
+		read, err := ReadGraphFile(&buf)
+		if err != nil {
+			t.Fatalf("reading back: %v\n%s", err, text)
+		}
+		if !g.Equal(read) {
+			t.Fatalf("round trip changed the graph\n%s", text)
+		}
+	})
+}
+
+func FuzzReadGraphFile(f *testing.F) {
+	f.Add("3 2\n2\n1 3\n2\n")
+	f.Add("2 1 11\n4 2 7\n5 1 7\n")
+	f.Add("% comment\n1 0\n\n")
+	f.Add("3 1 10\n1 2\n1 1\n1\n")
+	f.Add("3 2\n2\n\n1 3\n\n2\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		g, err := ReadGraphFile(strings.NewReader(input))
+		if err != nil {
+			return
+		}
+
+		// Whatever parses must be a structurally valid graph that survives
+		// a round trip
+		if g.NumVertices() > 0 {
+			if err := ValidateGraph(g.Xadj, g.Adjncy); err != nil {
+				t.Fatalf("reader accepted an invalid graph: %v", err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := WriteGraphFile(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadGraphFile(&buf)
+		if err != nil {
+			t.Fatalf("reading back: %v", err)
+		}
+		if !g.Equal(read) {
+			t.Fatal("round trip changed the graph")
+		}
+
+		// The two-pass reader agrees on input both accept
+		csr, err := ReadGraphFileCSR(writeTempGraph(t, []byte(input)))
+		if err == nil && !g.Equal(csr) {
+			t.Fatal("ReadGraphFileCSR disagrees with ReadGraphFile")
+		}
+	})
+}
+
+func TestEdgeIterator(t *testing.T) {
+	// Triangle 0-1-2 with a self-loop on 2 and weights by edge
+	g := &Graph{
+		Xadj:   []int32{0, 2, 4, 7},
+		Adjncy: []int32{1, 2, 0, 2, 0, 1, 2},
+		Adjwgt: []int32{5, 6, 5, 7, 6, 7, 9},
+	}
+
+	var edges [][3]int32
+	g.EdgeIterator(func(u, v, w int32) {
+		edges = append(edges, [3]int32{u, v, w})
+	})
+	assert.Equal(t, [][3]int32{{0, 1, 5}, {0, 2, 6}, {1, 2, 7}}, edges)
+
+	// Unit weights without Adjwgt, and one visit per grid edge
+	grid := NewGraph(createGridGraph(4, 3))
+	count := 0
+	grid.EdgeIterator(func(u, v, w int32) {
+		assert.Less(t, u, v)
+		assert.Equal(t, int32(1), w)
+		count++
+	})
+	assert.Equal(t, grid.NumEdges(), count)
+}
+
+func TestReadChacoFile(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		// Chaco and METIS agree on files without a code
+		input := "3 2\n2\n1 3\n2\n"
+		chaco, err := ReadChacoFile(strings.NewReader(input))
+		require.NoError(t, err)
+		fromMetis, err := ReadGraphFile(strings.NewReader(input))
+		require.NoError(t, err)
+		assert.True(t, chaco.Equal(fromMetis))
+	})
+
+	t.Run("VertexNumbers", func(t *testing.T) {
+		// Code 111: vertex number, vertex weight, then neighbor/weight pairs
+		input := "% numbered path\n3 2 111\n1 5 2 1.5\n2 6 1 1.5 3 2.2\n3 7 2 2.2\n"
+		g, err := ReadChacoFile(strings.NewReader(input))
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj)
+		assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy)
+		assert.Equal(t, []int32{5, 6, 7}, g.Vwgt)
+		assert.Equal(t, []int32{2, 2, 2, 2}, g.Adjwgt)
+
+		// The same code means vertex sizes to METIS
+		_, err = ReadGraphFile(strings.NewReader(input))
+		assert.Error(t, err)
+	})
+
+	for name, input := range map[string]string{
+		"Misnumbered":  "2 1 100\n2 2\n1 1\n",
+		"BadCode":      "2 1 200\n2\n1\n",
+		"NconField":    "2 1 10 1\n1 2\n1 1\n",
+		"BadWeight":    "2 1 1\n2 x\n1 1\n",
+		"WeightNaN":    "2 1 1\n2 NaN\n1 1\n",
+		"MissingLines": "2 1\n2\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadChacoFile(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRelabelBFS(t *testing.T) {
+	// A weighted 5x4 grid plus a separate edge 20-21
+	g := NewGraph(createGridGraph(5, 4))
+	g.Xadj = append(g.Xadj, g.Xadj[20]+1, g.Xadj[20]+2)
+	g.Adjncy = append(g.Adjncy, 21, 20)
+	g.Vwgt = make([]int32, 22)
+	for v := range g.Vwgt {
+		g.Vwgt[v] = int32(v)
+	}
+	g.Adjwgt = make([]int32, len(g.Adjncy))
+	for i := 0; i < g.NumVertices(); i++ {
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			g.Adjwgt[j] = int32(i) + g.Adjncy[j]
+		}
+	}
+
+	relabeled, perm := g.RelabelBFS(7)
+	require.NotNil(t, relabeled)
+	require.Len(t, perm, 22)
+	assert.Equal(t, int32(7), perm[0])
+	assert.Equal(t, int32(20), perm[20])
+
+	iperm := make([]int32, len(perm))
+	for k, v := range perm {
+		iperm[v] = int32(k)
+	}
+
+	// perm is an isomorphism: every edge and weight maps across, and the
+	// edge counts agree
+	assert.Equal(t, len(g.Adjncy), len(relabeled.Adjncy))
+	for k, v := range perm {
+		assert.Equal(t, g.Vwgt[v], relabeled.Vwgt[k])
+	}
+	weights := map[[2]int32]int32{}
+	relabeled.EdgeIterator(func(u, v, w int32) {
+		weights[[2]int32{u, v}] = w
+	})
+	g.EdgeIterator(func(u, v, w int32) {
+		a, b := iperm[u], iperm[v]
+		if a > b {
+			a, b = b, a
+		}
+		got, ok := weights[[2]int32{a, b}]
+		assert.True(t, ok, "edge %d-%d missing", u, v)
+		assert.Equal(t, w, got)
+	})
+
+	// BFS order: a vertex's neighbors are at most one level further out, so
+	// every vertex after the start has an earlier neighbor within its component
+	for k := 1; k < 20; k++ {
+		earlier := false
+		for _, u := range relabeled.Neighbors(k) {
+			earlier = earlier || int(u) < k
+		}
+		assert.True(t, earlier, "vertex %d", k)
+	}
+
+	relabeled, perm = g.RelabelBFS(22)
+	assert.Nil(t, relabeled)
+	assert.Nil(t, perm)
+}
+
+func TestSubsetCut(t *testing.T) {
+	g := NewGraph(createGridGraph(4, 4))
+
+	// The left half of a 4x4 grid is cut by one edge per row
+	left := []int32{0, 1, 4, 5, 8, 9, 12, 13}
+	assert.Equal(t, int32(4), g.SubsetCut(left))
+	assert.Equal(t, int32(4), g.SubsetCut(append(left, 0, 5)))
+
+	// A corner vertex and the empty and full sets
+	assert.Equal(t, int32(2), g.SubsetCut([]int32{0}))
+	assert.Zero(t, g.SubsetCut(nil))
+	all := make([]int32, 16)
+	for v := range all {
+		all[v] = int32(v)
+	}
+	assert.Zero(t, g.SubsetCut(all))
+
+	// Matches the edge cut of the corresponding bisection, with weights
+	g.Adjwgt = make([]int32, len(g.Adjncy))
+	for i := 0; i < 16; i++ {
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			g.Adjwgt[j] = int32(i) + g.Adjncy[j]
+		}
+	}
+	part := make([]int32, 16)
+	for v := range part {
+		part[v] = 1
+	}
+	for _, v := range left {
+		part[v] = 0
+	}
+	assert.Equal(t, CalculateEdgeCut(g, part), g.SubsetCut(left))
+}
+
+func TestSymmetrize(t *testing.T) {
+	// 0<->1 in both directions, 1->2, 2->3 twice and a self-loop on 3
+	xadj := []int32{0, 1, 3, 5, 6}
+	adjncy := []int32{1, 0, 2, 3, 3, 3}
+	adjwgt := []int32{3, 5, 2, 4, 1, 9}
+
+	g, err := Symmetrize(xadj, adjncy, adjwgt, WeightPolicyMax)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{0, 1, 3, 5, 6}, g.Xadj)
+	assert.Equal(t, []int32{1, 0, 2, 1, 3, 2}, g.Adjncy)
+	assert.Equal(t, []int32{5, 5, 2, 2, 5, 5}, g.Adjwgt)
+	assert.NoError(t, ValidateGraph(g.Xadj, g.Adjncy))
+
+	g, err = Symmetrize(xadj, adjncy, adjwgt, WeightPolicySum)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{8, 8, 2, 2, 5, 5}, g.Adjwgt)
+
+	g, err = Symmetrize(xadj, adjncy, nil, WeightPolicyMax)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 0, 2, 1, 3, 2}, g.Adjncy)
+	assert.Nil(t, g.Adjwgt)
+
+	_, err = Symmetrize(xadj, adjncy, adjwgt, WeightPolicy(9))
+	assert.Error(t, err)
+	_, err = Symmetrize(xadj, []int32{1, 0, 2, 3, 3, 4}, nil, WeightPolicyMax)
+	assert.Error(t, err)
+	_, err = Symmetrize(xadj, adjncy[:5], nil, WeightPolicyMax)
+	assert.Error(t, err)
+}
+
+func TestDirectedDegrees(t *testing.T) {
+	// 0->1, 0->2, 1->2, 2->0
+	g := NewGraph([]int32{0, 2, 3, 4}, []int32{1, 2, 2, 0})
+	in, out := g.DirectedDegrees()
+	assert.Equal(t, []int32{1, 1, 2}, in)
+	assert.Equal(t, []int32{2, 1, 1}, out)
+
+	// Undirected graphs have matching degrees
+	in, out = NewGraph(createGridGraph(3, 4)).DirectedDegrees()
+	assert.Equal(t, in, out)
+}
+
+func TestScaleEdgeWeights(t *testing.T) {
+	assert.Equal(t, []int32{1000, 500, 1, 1, 1, 1},
+		ScaleEdgeWeights([]float64{2.5, 1.25, 1e-6, 0, -3, math.NaN()}, 1000))
+
+	// Rounding to nearest
+	assert.Equal(t, []int32{10, 3, 4}, ScaleEdgeWeights([]float64{1, 0.34, 0.35}, 10))
+
+	// Without a positive weight everything becomes 1
+	assert.Equal(t, []int32{1, 1}, ScaleEdgeWeights([]float64{0, 0}, 100))
+	assert.Empty(t, ScaleEdgeWeights(nil, 100))
+	assert.Nil(t, ScaleEdgeWeights([]float64{1}, 0))
+}
+
+func TestConnectIsolated(t *testing.T) {
+	// Path 1-2-3 with isolated vertices 0, 4 and 5
+	//   0   1 - 2 - 3   4   5
+	g := &Graph{
+		Xadj:   []int32{0, 0, 1, 3, 4, 4, 4},
+		Adjncy: []int32{2, 1, 3, 2},
+		Adjwgt: []int32{7, 7, 8, 8},
+	}
+	assert.Equal(t, []int32{0, 4, 5}, g.IsolatedVertices())
+
+	anchored := g.ConnectIsolated(2)
+	require.NotNil(t, anchored)
+	assert.Empty(t, anchored.IsolatedVertices())
+	assert.Equal(t, []int32{1, 3, 0, 4, 5}, anchored.Neighbors(2))
+	assert.Equal(t, []int32{7, 8, 1, 1, 1}, anchored.Adjwgt[anchored.Xadj[2]:anchored.Xadj[3]])
+	_, ncomp := anchored.ConnectedComponents()
+	assert.Equal(t, int32(1), ncomp)
+	assert.NoError(t, checkEdgeWeightSymmetry(anchored.Xadj, anchored.Adjncy, anchored.Adjwgt))
+
+	// Nearest by id: 0 joins 1, 4 and 5 join 3
+	nearest := g.ConnectIsolated(-1)
+	require.NotNil(t, nearest)
+	assert.Equal(t, []int32{1}, nearest.Neighbors(0))
+	assert.Equal(t, []int32{2, 4, 5}, nearest.Neighbors(3))
+	_, ncomp = nearest.ConnectedComponents()
+	assert.Equal(t, int32(1), ncomp)
+
+	// Without any edges, everything joins vertex 0
+	empty := NewGraph([]int32{0, 0, 0, 0}, nil)
+	star := empty.ConnectIsolated(-1)
+	assert.Equal(t, []int32{1, 2}, star.Neighbors(0))
+	assert.Nil(t, star.Adjwgt)
+
+	assert.Nil(t, g.ConnectIsolated(6))
+}
+
+func TestParallelEdges(t *testing.T) {
+	// 0-1 listed twice, 1-2 three times, plus a self-loop on 2
+	g := &Graph{
+		Xadj:   []int32{0, 2, 7, 11},
+		Adjncy: []int32{1, 1, 2, 0, 2, 0, 2, 1, 2, 1, 1},
+		Adjwgt: []int32{3, 4, 1, 3, 1, 4, 1, 1, 9, 1, 1},
+	}
+	assert.Equal(t, 3, g.CountParallelEdges())
+
+	n := g.Normalize()
+	assert.Equal(t, []int32{0, 1, 3, 4}, n.Xadj)
+	assert.Equal(t, []int32{1, 0, 2, 1}, n.Adjncy)
+	assert.Equal(t, []int32{7, 7, 3, 3}, n.Adjwgt)
+	assert.Zero(t, n.CountParallelEdges())
+	assert.NoError(t, checkEdgeWeightSymmetry(n.Xadj, n.Adjncy, n.Adjwgt))
+
+	// A clean graph is unchanged
+	xadj, adjncy := createGridGraph(3, 3)
+	grid := NewGraph(xadj, adjncy)
+	assert.Zero(t, grid.CountParallelEdges())
+	assert.True(t, grid.Normalize().Equal(grid))
+}
+
+func TestStripSelfLoops(t *testing.T) {
+	// A matrix-derived path 1-2-3 with its diagonal: every vertex lists itself
+	g, err := ReadGraphFile(strings.NewReader("3 4 1\n1 5 2 1\n1 1 2 5 3 2\n2 2 3 5\n"))
+	require.NoError(t, err)
+
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+
+	assert.Equal(t, 3, g.StripSelfLoops())
+	assert.Equal(t, []int32{0, 1, 3, 4}, g.Xadj)
+	assert.Equal(t, []int32{1, 0, 2, 1}, g.Adjncy)
+	assert.Equal(t, []int32{1, 1, 2, 2}, g.Adjwgt)
+	assert.Equal(t, []string{"warn: StripSelfLoops: removed 3 self-loops"}, rec.messages)
+
+	// Nothing left to strip
+	assert.Zero(t, g.StripSelfLoops())
+	assert.Len(t, rec.messages, 1)
+	assert.NoError(t, ValidateGraph(g.Xadj, g.Adjncy))
+}
+
+func TestShiftBase(t *testing.T) {
+	part := []int32{0, 2, 1}
+	ShiftBase(part, 1)
+	assert.Equal(t, []int32{1, 3, 2}, part)
+	ShiftBase(part, -1)
+	assert.Equal(t, []int32{0, 2, 1}, part)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePartitioningBase(&buf, part, 1))
+	assert.Equal(t, "1\n3\n2\n", buf.String())
+	buf.Reset()
+	require.NoError(t, WritePartitioning(&buf, part))
+	assert.Equal(t, "0\n2\n1\n", buf.String())
+}
+
+func TestMultiConstraintWeights(t *testing.T) {
+	// Two constraints per vertex: computation and memory
+	part := []int32{0, 1, 0, 1}
+	vwgt := []int32{
+		3, 10,
+		1, 20,
+		2, 30,
+		4, 40,
+	}
+	assert.Equal(t, [][]int64{{5, 40}, {5, 60}}, MultiConstraintWeights(part, vwgt, 2, 2))
+
+	// An empty partition has zero weight under every constraint
+	assert.Equal(t, [][]int64{{5, 40}, {5, 60}, {0, 0}}, MultiConstraintWeights(part, vwgt, 2, 3))
+
+	assert.Nil(t, MultiConstraintWeights(part, vwgt[:6], 2, 2))
+	assert.Nil(t, MultiConstraintWeights(part, vwgt, 0, 2))
+}
+
+func TestGraphLabels(t *testing.T) {
+	// Path a-b-c-d
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 5, 6},
+		Adjncy: []int32{1, 0, 2, 1, 3, 2},
+		Labels: []string{"a", "b", "c", "d"},
+	}
+	assert.Equal(t, "c", g.Label(2))
+	assert.Equal(t, "7", g.Label(7))
+	assert.Equal(t, "1", NewGraph(g.Xadj, g.Adjncy).Label(1))
+
+	sub := g.Subgraph([]int32{3, 1, 2})
+	assert.Equal(t, []string{"d", "b", "c"}, sub.Labels)
+	assert.Equal(t, g.Labels, g.Normalize().Labels)
+	assert.Equal(t, g.Labels, g.FilterEdges(1).Labels)
+	assert.Equal(t, g.Labels, g.ConnectIsolated(-1).Labels)
+	relabeled, _ := g.RelabelBFS(2)
+	assert.Equal(t, []string{"c", "b", "d", "a"}, relabeled.Labels)
+	assert.Nil(t, NewGraph(g.Xadj, g.Adjncy).Subgraph([]int32{0, 1}).Labels)
+
+	// Equal ignores labels
+	renamed := *g
+	renamed.Labels = []string{"a", "b", "c", "x"}
+	assert.True(t, g.Equal(&renamed))
+	assert.False(t, g.EqualLabels(&renamed))
+}
+
+func TestGraphLabelsEncoding(t *testing.T) {
+	g := &Graph{
+		Xadj:   []int32{0, 1, 2},
+		Adjncy: []int32{1, 0},
+		Vwgt:   []int32{3, 4},
+		Labels: []string{"left", "right"},
+	}
+	unlabeled := NewGraph(g.Xadj, g.Adjncy)
+
+	// JSON carries the labels, and leaves the field out when there are none
+	data, err := json.Marshal(g)
+	require.NoError(t, err)
+	var fromJSON Graph
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.True(t, g.Equal(&fromJSON))
+	assert.Equal(t, []string{"left", "right"}, fromJSON.Labels)
+
+	data, err = json.Marshal(unlabeled)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Labels")
+	fromJSON = Graph{}
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Nil(t, fromJSON.Labels)
+
+	// So does gob, which never sends empty fields
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(g))
+	var fromGob Graph
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&fromGob))
+	assert.True(t, g.Equal(&fromGob))
+	assert.Equal(t, []string{"left", "right"}, fromGob.Labels)
+
+	buf.Reset()
+	require.NoError(t, gob.NewEncoder(&buf).Encode(unlabeled))
+	fromGob = Graph{}
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&fromGob))
+	assert.True(t, unlabeled.Equal(&fromGob))
+	assert.Nil(t, fromGob.Labels)
+}
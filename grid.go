@@ -0,0 +1,186 @@
+package metis
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Common stencils for StencilGraph, as neighbor offsets in (x, y[, z]) order
+var (
+	Stencil5Point = [][]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	Stencil9Point = [][]int32{
+		{-1, -1}, {0, -1}, {1, -1},
+		{-1, 0}, {1, 0},
+		{-1, 1}, {0, 1}, {1, 1},
+	}
+	Stencil7Point = [][]int32{
+		{-1, 0, 0}, {1, 0, 0},
+		{0, -1, 0}, {0, 1, 0},
+		{0, 0, -1}, {0, 0, 1},
+	}
+)
+
+// StencilGraph builds the graph of a structured grid whose connectivity is
+// given by a stencil. dims holds the number of points along each dimension and
+// stencil lists the neighbor offsets of a point, one entry per dimension, for
+// example Stencil5Point for the 2D 5-point stencil. Points are numbered with
+// the first dimension varying fastest, so point (x, y, z) is vertex
+// x + dims[0]*(y + dims[1]*z). Each adjacency list is sorted and free of
+// duplicates and self-loops.
+//
+// periodic selects, per dimension, whether neighbors wrap around the grid
+// boundary as on a torus; nil means no dimension is periodic. Neighbors
+// falling outside a non-periodic dimension are dropped. A periodic grid has no
+// boundary, so every point has the same degree.
+//
+// The stencil should contain the negation of each of its offsets; otherwise
+// the resulting graph is not symmetric. StencilGraph returns nil if dims is
+// empty or holds a non-positive size, or if an offset or periodic has the
+// wrong length.
+func StencilGraph(dims []int32, stencil [][]int32, periodic []bool) *Graph {
+	if len(dims) == 0 {
+		return nil
+	}
+	if periodic != nil && len(periodic) != len(dims) {
+		return nil
+	}
+	nvtxs := 1
+	for _, d := range dims {
+		if d <= 0 {
+			return nil
+		}
+		nvtxs *= int(d)
+	}
+	for _, off := range stencil {
+		if len(off) != len(dims) {
+			return nil
+		}
+	}
+
+	g := &Graph{
+		Xadj:   make([]int32, nvtxs+1),
+		Adjncy: make([]int32, 0, nvtxs*len(stencil)),
+	}
+
+	coord := make([]int32, len(dims))
+	for v := 0; v < nvtxs; v++ {
+		start := len(g.Adjncy)
+		for _, off := range stencil {
+			u, ok := stencilNeighbor(dims, coord, off, periodic)
+			if ok && u != int32(v) {
+				g.Adjncy = append(g.Adjncy, u)
+			}
+		}
+
+		// Offsets may reach the same neighbor when a periodic dimension is
+		// shorter than the stencil, so sort and drop duplicates
+		row := g.Adjncy[start:]
+		sort.Slice(row, func(a, b int) bool { return row[a] < row[b] })
+		n := 0
+		for i, u := range row {
+			if i == 0 || u != row[n-1] {
+				row[n] = u
+				n++
+			}
+		}
+		g.Adjncy = g.Adjncy[:start+n]
+		g.Xadj[v+1] = int32(len(g.Adjncy))
+
+		// Advance the coordinate of the next point, first dimension fastest
+		for d := range coord {
+			coord[d]++
+			if coord[d] < dims[d] {
+				break
+			}
+			coord[d] = 0
+		}
+	}
+
+	return g
+}
+
+// stencilNeighbor returns the vertex at coord+off, wrapping periodic
+// dimensions, or false if it lies outside the grid
+func stencilNeighbor(dims, coord, off []int32, periodic []bool) (int32, bool) {
+	u, stride := int32(0), int32(1)
+	for d := range dims {
+		c := coord[d] + off[d]
+		if periodic != nil && periodic[d] {
+			c %= dims[d]
+			if c < 0 {
+				c += dims[d]
+			}
+		} else if c < 0 || c >= dims[d] {
+			return 0, false
+		}
+		u += c * stride
+		stride *= dims[d]
+	}
+	return u, true
+}
+
+// GenerateGrid2D returns the nx by ny grid graph of the 5-point stencil,
+// wrapping around in both directions if periodic is set
+func GenerateGrid2D(nx, ny int32, periodic bool) *Graph {
+	return StencilGraph([]int32{nx, ny}, Stencil5Point, []bool{periodic, periodic})
+}
+
+// GenerateGrid3D returns the nx by ny by nz grid graph of the 7-point stencil,
+// wrapping around in all directions if periodic is set
+func GenerateGrid3D(nx, ny, nz int32, periodic bool) *Graph {
+	return StencilGraph([]int32{nx, ny, nz}, Stencil7Point, []bool{periodic, periodic, periodic})
+}
+
+// GenerateBarabasiAlbert returns a scale-free graph of nvtxs vertices grown by
+// preferential attachment: it starts from a clique of m+1 vertices and joins
+// every further vertex to m distinct earlier vertices, each chosen with
+// probability proportional to its degree. The few hub vertices this produces
+// are hard on coarsening, unlike grids. The graph has m(m+1)/2 +
+// (nvtxs-m-1)*m edges, sorted adjacency lists and unit weights, and the same
+// seed always yields the same graph. If nvtxs <= m+1 the result is the
+// complete graph on nvtxs vertices. GenerateBarabasiAlbert returns nil if
+// nvtxs or m is not positive.
+func GenerateBarabasiAlbert(nvtxs, m int32, seed int64) *Graph {
+	if nvtxs < 1 || m < 1 {
+		return nil
+	}
+	rng := rand.New(rand.NewSource(seed))
+	mg := NewMutableGraph(int(nvtxs))
+
+	// Every edge adds both endpoints to ends, so a uniform draw from it picks
+	// a vertex with probability proportional to its degree
+	var ends []int32
+	addEdge := func(u, v int32) {
+		mg.AddEdge(u, v, 1)
+		ends = append(ends, u, v)
+	}
+
+	seedSize := m + 1
+	if seedSize > nvtxs {
+		seedSize = nvtxs
+	}
+	for u := int32(0); u < seedSize; u++ {
+		for v := u + 1; v < seedSize; v++ {
+			addEdge(u, v)
+		}
+	}
+
+	targets := make([]int32, 0, m)
+	for v := seedSize; v < nvtxs; v++ {
+		targets = targets[:0]
+		for int32(len(targets)) < m {
+			u := ends[rng.Intn(len(ends))]
+			dup := false
+			for _, t := range targets {
+				dup = dup || t == u
+			}
+			if !dup {
+				targets = append(targets, u)
+			}
+		}
+		for _, u := range targets {
+			addEdge(v, u)
+		}
+	}
+	return mg.Freeze()
+}
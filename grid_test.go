@@ -0,0 +1,107 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStencilGraph(t *testing.T) {
+	t.Run("FivePoint", func(t *testing.T) {
+		g := StencilGraph([]int32{4, 3}, Stencil5Point, nil)
+		require.NotNil(t, g)
+
+		xadj, adjncy := createGridGraph(4, 3)
+		assert.Equal(t, xadj, g.Xadj)
+		assert.Equal(t, adjncy, g.Adjncy)
+		assert.Equal(t, g, GenerateGrid2D(4, 3, false))
+	})
+
+	t.Run("NinePoint", func(t *testing.T) {
+		g := StencilGraph([]int32{3, 3}, Stencil9Point, nil)
+		require.NotNil(t, g)
+
+		// Corners see 3 neighbors, edge midpoints 5 and the center all 8
+		assert.Equal(t, []int32{3, 5, 3, 5, 8, 5, 3, 5, 3}, g.DegreeSequence())
+		assert.Equal(t, []int32{0, 1, 2, 3, 5, 6, 7, 8}, g.Neighbors(4))
+		assert.Equal(t, 20, g.NumEdges())
+		assert.NoError(t, checkEdgeWeightSymmetry(g.Xadj, g.Adjncy, make([]int32, len(g.Adjncy))))
+	})
+
+	t.Run("ThreeD", func(t *testing.T) {
+		g := GenerateGrid3D(3, 3, 3, false)
+		require.NotNil(t, g)
+		assert.Equal(t, 27, g.NumVertices())
+		assert.Equal(t, 54, g.NumEdges())
+		assert.Equal(t, 6, g.Degree(13))
+		assert.Equal(t, []int32{4, 10, 12, 14, 16, 22}, g.Neighbors(13))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Nil(t, StencilGraph(nil, Stencil5Point, nil))
+		assert.Nil(t, StencilGraph([]int32{3, 0}, Stencil5Point, nil))
+		assert.Nil(t, StencilGraph([]int32{3, 3, 3}, Stencil5Point, nil))
+		assert.Nil(t, StencilGraph([]int32{3, 3}, Stencil5Point, []bool{true}))
+	})
+}
+
+func TestPeriodicGrid(t *testing.T) {
+	uniform := func(t *testing.T, g *Graph, degree int32) {
+		t.Helper()
+		require.NotNil(t, g)
+		for v, d := range g.DegreeSequence() {
+			assert.Equal(t, degree, d, "vertex %d", v)
+		}
+		assert.NoError(t, checkEdgeWeightSymmetry(g.Xadj, g.Adjncy, make([]int32, len(g.Adjncy))))
+	}
+
+	uniform(t, GenerateGrid2D(5, 4, true), 4)
+	uniform(t, GenerateGrid3D(3, 4, 5, true), 6)
+	uniform(t, StencilGraph([]int32{4, 4}, Stencil9Point, []bool{true, true}), 8)
+
+	// Vertex 0 wraps to the far end of both rows
+	g := GenerateGrid2D(5, 4, true)
+	assert.Equal(t, []int32{1, 4, 5, 15}, g.Neighbors(0))
+
+	// Periodic in x only: a cylinder with boundary rows of degree 3
+	cyl := StencilGraph([]int32{5, 4}, Stencil5Point, []bool{true, false})
+	assert.Equal(t, 3, cyl.Degree(0))
+	assert.Equal(t, 4, cyl.Degree(5))
+
+	// A periodic dimension of length 2 reaches the same neighbor both ways
+	uniform(t, GenerateGrid2D(2, 3, true), 3)
+}
+
+func TestGenerateBarabasiAlbert(t *testing.T) {
+	g := GenerateBarabasiAlbert(200, 3, 1)
+	require.NotNil(t, g)
+	assert.Equal(t, 200, g.NumVertices())
+	assert.Equal(t, 3*4/2+(200-4)*3, g.NumEdges())
+	assert.NoError(t, ValidateGraph(g.Xadj, g.Adjncy))
+	assert.NoError(t, checkEdgeWeightSymmetry(g.Xadj, g.Adjncy, make([]int32, len(g.Adjncy))))
+	assert.Nil(t, g.Vwgt)
+	assert.Nil(t, g.Adjwgt)
+
+	// Every vertex keeps at least m neighbors, and hubs emerge
+	maxDegree := 0
+	for v := 0; v < g.NumVertices(); v++ {
+		assert.GreaterOrEqual(t, g.Degree(v), 3, "vertex %d", v)
+		if g.Degree(v) > maxDegree {
+			maxDegree = g.Degree(v)
+		}
+	}
+	assert.Greater(t, maxDegree, 15)
+
+	// Deterministic for a seed
+	assert.True(t, g.Equal(GenerateBarabasiAlbert(200, 3, 1)))
+	assert.False(t, g.Equal(GenerateBarabasiAlbert(200, 3, 2)))
+
+	// Too few vertices for the seed clique: the complete graph
+	k := GenerateBarabasiAlbert(3, 5, 1)
+	require.NotNil(t, k)
+	assert.Equal(t, 3, k.NumEdges())
+
+	assert.Nil(t, GenerateBarabasiAlbert(0, 2, 1))
+	assert.Nil(t, GenerateBarabasiAlbert(10, 0, 1))
+}
@@ -0,0 +1,49 @@
+package metis
+
+import "sync"
+
+// Logger receives the diagnostics of the package: retries, fallbacks and
+// other decisions made on the caller's behalf that do not warrant an error.
+// Implementations must be safe for concurrent use. Adapters for log/slog,
+// zap or logrus are a few lines each.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// nopLogger discards everything; it is the default Logger
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = nopLogger{}
+)
+
+// SetLogger routes the package diagnostics to l. nil restores the default,
+// which discards them. The package never prints to stdout or stderr itself.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	loggerMu.Lock()
+	logger = l
+	loggerMu.Unlock()
+}
+
+// currentLogger returns the Logger set by SetLogger
+func currentLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+func debugf(format string, args ...interface{}) {
+	currentLogger().Debugf(format, args...)
+}
+
+func warnf(format string, args ...interface{}) {
+	currentLogger().Warnf(format, args...)
+}
@@ -0,0 +1,46 @@
+package metis
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger keeps every message it receives
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug: " + fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn: " + fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.mu.Lock()
+	l.messages = append(l.messages, msg)
+	l.mu.Unlock()
+}
+
+func TestSetLogger(t *testing.T) {
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+
+	xadj, adjncy := createGridGraph(3, 3)
+	require.NoError(t, PartitionAndReport(NewGraph(xadj, adjncy), 2, t.TempDir(), nil, 0))
+	assert.Equal(t, []string{"debug: PartitionAndReport: 9 vertices exceed the DOT limit of 0, skipping partition.dot"}, rec.messages)
+
+	// Back to discarding
+	SetLogger(nil)
+	debugf("dropped")
+	assert.Len(t, rec.messages, 1)
+	assert.IsType(t, nopLogger{}, currentLogger())
+}
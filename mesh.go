@@ -0,0 +1,422 @@
+package metis
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// CompactMeshNodes removes nodes that are not referenced by any element and
+// renumbers the remaining nodes contiguously.
+//
+// The mesh functions size their node arrays from nn, so passing an nn that is
+// larger than the number of nodes actually used in eind wastes memory and
+// leaves npart with meaningless entries for the unused nodes. Unreferenced
+// nodes also show up as isolated vertices in the nodal graph, which can confuse
+// nodal partitioning.
+//
+// The returned nodeMap has newNn entries, where nodeMap[i] is the original id
+// of compacted node i. A node partition computed on the compacted mesh is
+// lifted back with npart[nodeMap[i]] = newNpart[i].
+func CompactMeshNodes(ne, nn int32, eptr, eind []int32) (newNn int32, newEind []int32, nodeMap []int32) {
+	// Mark referenced nodes
+	used := make([]bool, nn)
+	for i := eptr[0]; i < eptr[ne]; i++ {
+		used[eind[i]] = true
+	}
+
+	// Assign new ids in original order so relative numbering is preserved
+	newID := make([]int32, nn)
+	for i := int32(0); i < nn; i++ {
+		if used[i] {
+			newID[i] = newNn
+			nodeMap = append(nodeMap, i)
+			newNn++
+		}
+	}
+
+	newEind = make([]int32, len(eind))
+	for i := eptr[0]; i < eptr[ne]; i++ {
+		newEind[i] = newID[eind[i]]
+	}
+
+	return newNn, newEind, nodeMap
+}
+
+// MeshStatistics summarizes the element sizes and node usage of a mesh, as
+// computed by MeshStats
+type MeshStatistics struct {
+	Elements           int32   // Number of elements, ne
+	Nodes              int32   // Number of nodes, nn
+	MinNodesPerElement int32   // Smallest element
+	MaxNodesPerElement int32   // Largest element
+	AvgNodesPerElement float64 // Mean element size
+	UnreferencedNodes  int32   // Nodes in [0, nn) that no element uses
+	Homogeneous        bool    // Whether all elements have the same size
+}
+
+// MeshStats summarizes a mesh before partitioning. Unreferenced nodes waste
+// space in the node partition and become isolated vertices of the nodal graph
+// (see CompactMeshNodes), and a mixed mesh usually needs a smaller ncommon
+// than its largest element suggests (see RecommendNcommon). The mesh is
+// assumed to pass ValidateMesh.
+func MeshStats(ne, nn int32, eptr, eind []int32) MeshStatistics {
+	stats := MeshStatistics{Elements: ne, Nodes: nn}
+	if ne > 0 {
+		stats.MinNodesPerElement = eptr[1] - eptr[0]
+		stats.MaxNodesPerElement = stats.MinNodesPerElement
+		stats.AvgNodesPerElement = float64(eptr[ne]-eptr[0]) / float64(ne)
+	}
+	for e := int32(1); e < ne; e++ {
+		size := eptr[e+1] - eptr[e]
+		if size < stats.MinNodesPerElement {
+			stats.MinNodesPerElement = size
+		}
+		if size > stats.MaxNodesPerElement {
+			stats.MaxNodesPerElement = size
+		}
+	}
+	stats.Homogeneous = stats.MinNodesPerElement == stats.MaxNodesPerElement
+
+	used := make([]bool, nn)
+	for _, n := range eind[eptr[0]:eptr[ne]] {
+		used[n] = true
+	}
+	for _, u := range used {
+		if !u {
+			stats.UnreferencedNodes++
+		}
+	}
+
+	return stats
+}
+
+// FindDuplicateElements returns groups of elements that are defined on the
+// same set of nodes, regardless of node order. Each group lists element ids in
+// increasing order and groups are ordered by their first element. Duplicated
+// elements inflate the dual graph and skew partitioning, so this is a useful
+// check to run before PartMeshDual.
+func FindDuplicateElements(ne int32, eptr, eind []int32) [][]int32 {
+	// Hash the sorted node list of each element and compare within a bucket
+	// so that hash collisions never merge distinct elements
+	buckets := make(map[uint64][]int)
+	var groups, groupNodes [][]int32
+	h := fnv.New64a()
+	buf := make([]byte, 4)
+
+	for e := int32(0); e < ne; e++ {
+		nodes := append([]int32(nil), eind[eptr[e]:eptr[e+1]]...)
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+		h.Reset()
+		for _, n := range nodes {
+			buf[0], buf[1], buf[2], buf[3] = byte(n), byte(n>>8), byte(n>>16), byte(n>>24)
+			h.Write(buf)
+		}
+		key := h.Sum64()
+
+		found := false
+		for _, gi := range buckets[key] {
+			if equalInt32(groupNodes[gi], nodes) {
+				groups[gi] = append(groups[gi], e)
+				found = true
+				break
+			}
+		}
+		if !found {
+			buckets[key] = append(buckets[key], len(groups))
+			groups = append(groups, []int32{e})
+			groupNodes = append(groupNodes, nodes)
+		}
+	}
+
+	var dups [][]int32
+	for _, g := range groups {
+		if len(g) > 1 {
+			dups = append(dups, g)
+		}
+	}
+	return dups
+}
+
+// equalInt32 reports whether two slices hold the same values
+func equalInt32(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MeshToNodalWeighted builds the nodal graph of a mesh, like MeshToNodal, with
+// Adjwgt set to the number of elements each pair of adjacent nodes shares.
+// Nodes on a common face or edge of several elements are coupled more
+// strongly than nodes meeting in a single element, and partitioning the
+// weighted graph avoids cutting through those stronger couplings.
+func MeshToNodalWeighted(ne, nn int32, eptr, eind []int32) (*Graph, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return nil, err
+	}
+
+	xadj, adjncy, err := MeshToNodal(ne, nn, eptr, eind)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the elements around every node
+	nptr := make([]int32, nn+1)
+	for _, n := range eind {
+		nptr[n+1]++
+	}
+	for n := int32(0); n < nn; n++ {
+		nptr[n+1] += nptr[n]
+	}
+	nind := make([]int32, nptr[nn])
+	fill := append([]int32(nil), nptr[:nn]...)
+	for e := int32(0); e < ne; e++ {
+		for _, n := range eind[eptr[e]:eptr[e+1]] {
+			nind[fill[n]] = e
+			fill[n]++
+		}
+	}
+
+	// Count shared elements per neighbor, then read the counts off along the
+	// adjacency list and clear them for the next node
+	shared := make([]int32, nn)
+	adjwgt := make([]int32, len(adjncy))
+	for v := int32(0); v < nn; v++ {
+		for _, e := range nind[nptr[v]:nptr[v+1]] {
+			for _, u := range eind[eptr[e]:eptr[e+1]] {
+				if u != v {
+					shared[u]++
+				}
+			}
+		}
+		for j := xadj[v]; j < xadj[v+1]; j++ {
+			adjwgt[j] = shared[adjncy[j]]
+		}
+		for _, e := range nind[nptr[v]:nptr[v+1]] {
+			for _, u := range eind[eptr[e]:eptr[e+1]] {
+				shared[u] = 0
+			}
+		}
+	}
+
+	return &Graph{Xadj: xadj, Adjncy: adjncy, Adjwgt: adjwgt}, nil
+}
+
+// PartMeshNodalWeightedNodes partitions a mesh by its nodes, balancing
+// nodeWeights (one per node, nil for unit weights) and minimizing a cut in
+// which every pair of adjacent nodes counts once per element they share, the
+// graph built by MeshToNodalWeighted. It returns the edge cut of that graph
+// and the element and node partitions; each element goes to the partition
+// holding most of its nodes, the lowest such partition on ties.
+//
+// Choose the balance by where the work is. Element-balanced partitioning
+// (PartMeshDual) suits element-wise work such as assembly and finite volume
+// fluxes. Node-balanced partitioning suits work per unknown at the nodes, such
+// as the solve of a nodal finite element system; PartMeshNodal balances nodes
+// too, but treats every node adjacency alike, while this function keeps nodes
+// coupled through many elements together.
+func PartMeshNodalWeightedNodes(ne, nn int32, eptr, eind, nodeWeights []int32, nparts int32, options []int32) (int32, []int32, []int32, error) {
+	if nodeWeights != nil && len(nodeWeights) != int(nn) {
+		return 0, nil, nil, fmt.Errorf("nodeWeights has %d entries, want nn = %d", len(nodeWeights), nn)
+	}
+
+	g, err := MeshToNodalWeighted(ne, nn, eptr, eind)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	g.Vwgt = nodeWeights
+
+	npart, objval, err := PartitionGraph(g, nparts, options)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	epart := make([]int32, ne)
+	count := make([]int32, nparts)
+	for e := int32(0); e < ne; e++ {
+		nodes := eind[eptr[e]:eptr[e+1]]
+		best := int32(-1)
+		for _, n := range nodes {
+			p := npart[n]
+			count[p]++
+			if best < 0 || count[p] > count[best] || count[p] == count[best] && p < best {
+				best = p
+			}
+		}
+		for _, n := range nodes {
+			count[npart[n]] = 0
+		}
+		if best < 0 {
+			best = 0 // An element without nodes
+		}
+		epart[e] = best
+	}
+
+	return objval, epart, npart, nil
+}
+
+// MeshElementFaceCounts returns, for each element, the number of interface
+// faces it has: the number of other elements sharing at least ncommon nodes
+// with it, its degree in the dual graph MeshToDual builds. Boundary faces on
+// the outside of the mesh are not counted, since they never carry data
+// between partitions. An element on a partition boundary sends its data once
+// to each neighboring partition, so the count is a natural vsize for
+// PartMeshDual when OptionObjType is ObjectiveVol: elements with many
+// neighbors cost more to place on a boundary.
+//
+// The counts come from MeshToDual, so the error is that of MeshToDual: a
+// malformed mesh rejected by ValidateMesh, or a failure inside METIS. Both
+// are reported rather than answered with a nil or partial slice, which as a
+// vsize would silently skew the partition.
+func MeshElementFaceCounts(ne, nn int32, eptr, eind []int32, ncommon int32) ([]int32, error) {
+	xadj, _, err := MeshToDual(ne, nn, eptr, eind, ncommon)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int32, ne)
+	for e := range counts {
+		counts[e] = xadj[e+1] - xadj[e]
+	}
+	return counts, nil
+}
+
+// ElementType identifies the shape of the elements of a homogeneous mesh
+type ElementType int
+
+// Element types
+const (
+	ElementLine ElementType = iota
+	ElementTriangle
+	ElementQuadrilateral
+	ElementTetrahedron
+	ElementHexahedron
+	ElementPrism
+	ElementPyramid
+)
+
+// RecommendNcommon returns the conventional ncommon value for a mesh made of
+// the given element type, i.e. the number of nodes on the facet two elements
+// share when they are face neighbors: 2 for 2D elements sharing an edge, 3 for
+// tetrahedra sharing a triangular face and 4 for hexahedra sharing a quad face.
+// Prisms and pyramids have triangular faces, so 3 is used to keep those
+// neighbors connected. Unknown element types return 1.
+//
+// ncommon controls which elements are adjacent in the dual graph. A value that
+// is too small also connects elements touching only at an edge or a corner,
+// producing a much denser dual whose edge cut no longer counts shared faces. A
+// value that is too large drops genuine face neighbors, which can leave the
+// dual disconnected and yield scattered, non-contiguous partitions.
+func RecommendNcommon(elementType ElementType) int32 {
+	switch elementType {
+	case ElementLine:
+		return 1
+	case ElementTriangle, ElementQuadrilateral:
+		return 2
+	case ElementTetrahedron, ElementPrism, ElementPyramid:
+		return 3
+	case ElementHexahedron:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// MeshPartitioner partitions a fixed mesh repeatedly through its dual graph.
+// The dual graph is built with MeshToDual on first use and reused by every
+// subsequent call, so sweeps over nparts or seeds pay for its construction once.
+//
+// Invalidation contract: the partitioner keeps its own copy of the mesh
+// connectivity, so later changes to the caller's eptr/eind slices have no
+// effect. The cached dual is discarded only when the mesh is replaced with
+// SetMesh or when Invalidate is called. A MeshPartitioner is safe for
+// concurrent use; calls are serialized internally.
+type MeshPartitioner struct {
+	mu      sync.Mutex
+	ne, nn  int32
+	eptr    []int32
+	eind    []int32
+	ncommon int32
+	dual    *Graph
+}
+
+// NewMeshPartitioner creates a partitioner for the given mesh. ncommon is the
+// number of common nodes two elements must share to be adjacent in the dual.
+func NewMeshPartitioner(ne, nn int32, eptr, eind []int32, ncommon int32) *MeshPartitioner {
+	m := &MeshPartitioner{ncommon: ncommon}
+	m.setMesh(ne, nn, eptr, eind)
+	return m
+}
+
+// SetMesh replaces the mesh connectivity and invalidates the cached dual graph
+func (m *MeshPartitioner) SetMesh(ne, nn int32, eptr, eind []int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setMesh(ne, nn, eptr, eind)
+}
+
+// Invalidate discards the cached dual graph so the next call rebuilds it
+func (m *MeshPartitioner) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dual = nil
+}
+
+// Dual returns the cached dual graph, building it if necessary. The returned
+// graph is shared with the partitioner and must not be modified.
+func (m *MeshPartitioner) Dual() (*Graph, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buildDual()
+}
+
+// PartDual partitions the mesh elements into nparts using the cached dual
+// graph. Like METIS_PartMeshDual, OptionPType selects recursive bisection or
+// k-way partitioning. It returns the element partition and the edge cut of
+// the dual graph.
+func (m *MeshPartitioner) PartDual(nparts int32, options []int32) ([]int32, int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dual, err := m.buildDual()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if options != nil && len(options) == NoOptions && PType(options[OptionPType]) == MethodRB {
+		return PartGraphRecursive(dual.Xadj, dual.Adjncy, nparts, options)
+	}
+	return PartGraphKway(dual.Xadj, dual.Adjncy, nparts, options)
+}
+
+// setMesh stores a private copy of the connectivity; callers must hold mu
+func (m *MeshPartitioner) setMesh(ne, nn int32, eptr, eind []int32) {
+	m.ne = ne
+	m.nn = nn
+	m.eptr = append([]int32(nil), eptr...)
+	m.eind = append([]int32(nil), eind...)
+	m.dual = nil
+}
+
+// buildDual returns the cached dual, computing it on first use; callers must hold mu
+func (m *MeshPartitioner) buildDual() (*Graph, error) {
+	if m.dual != nil {
+		return m.dual, nil
+	}
+
+	xadj, adjncy, err := MeshToDual(m.ne, m.nn, m.eptr, m.eind, m.ncommon)
+	if err != nil {
+		return nil, err
+	}
+
+	m.dual = NewGraph(xadj, adjncy)
+	return m.dual, nil
+}
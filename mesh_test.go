@@ -0,0 +1,397 @@
+package metis
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactMeshNodes(t *testing.T) {
+	// Two triangles that only use nodes 1, 3, 4 and 7 out of 9
+	ne := int32(2)
+	nn := int32(9)
+	eptr := []int32{0, 3, 6}
+	eind := []int32{1, 3, 4, 3, 7, 4}
+
+	newNn, newEind, nodeMap := CompactMeshNodes(ne, nn, eptr, eind)
+	assert.Equal(t, int32(4), newNn)
+	assert.Equal(t, []int32{0, 1, 2, 1, 3, 2}, newEind)
+	assert.Equal(t, []int32{1, 3, 4, 7}, nodeMap)
+
+	// Compacted connectivity maps back to the original through nodeMap
+	for i, n := range newEind {
+		assert.Equal(t, eind[i], nodeMap[n])
+	}
+
+	// Input is left untouched
+	assert.Equal(t, []int32{1, 3, 4, 3, 7, 4}, eind)
+
+	t.Run("NoGaps", func(t *testing.T) {
+		eind := []int32{0, 1, 2, 1, 3, 2}
+		newNn, newEind, nodeMap := CompactMeshNodes(ne, 4, eptr, eind)
+		assert.Equal(t, int32(4), newNn)
+		assert.Equal(t, eind, newEind)
+		assert.Equal(t, []int32{0, 1, 2, 3}, nodeMap)
+	})
+
+	t.Run("PartitionCompacted", func(t *testing.T) {
+		opts := make([]int32, NoOptions)
+		SetDefaultOptions(opts)
+
+		_, epart, npart, err := PartMeshNodal(ne, newNn, eptr, newEind, nil, nil, 2, nil, opts)
+		require.NoError(t, err)
+		assert.Len(t, epart, int(ne))
+		require.Len(t, npart, int(newNn))
+
+		// Lift the node partition back to the original numbering
+		lifted := make([]int32, nn)
+		for i := range lifted {
+			lifted[i] = -1
+		}
+		for i, p := range npart {
+			lifted[nodeMap[i]] = p
+		}
+		for _, unused := range []int{0, 2, 5, 6, 8} {
+			assert.Equal(t, int32(-1), lifted[unused])
+		}
+	})
+}
+
+func TestMeshPartitioner(t *testing.T) {
+	// Strip of 8 triangles over a 2x5 node lattice
+	ne := int32(8)
+	nn := int32(10)
+	eptr := []int32{0, 3, 6, 9, 12, 15, 18, 21, 24}
+	eind := []int32{
+		0, 1, 5, 1, 6, 5,
+		1, 2, 6, 2, 7, 6,
+		2, 3, 7, 3, 8, 7,
+		3, 4, 8, 4, 9, 8,
+	}
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	mp := NewMeshPartitioner(ne, nn, eptr, eind, 2)
+
+	dual, err := mp.Dual()
+	require.NoError(t, err)
+	assert.Equal(t, int(ne), dual.NumVertices())
+
+	for _, nparts := range []int32{2, 3, 4} {
+		epart, objval, err := mp.PartDual(nparts, opts)
+		require.NoError(t, err)
+		assert.Len(t, epart, int(ne))
+		assert.Equal(t, CalculateEdgeCut(dual, epart), objval)
+	}
+
+	// The dual is reused across calls
+	again, err := mp.Dual()
+	require.NoError(t, err)
+	assert.Same(t, dual, again)
+
+	// Mutating the caller's slices does not affect the cached mesh
+	eind[0] = 9
+	again, err = mp.Dual()
+	require.NoError(t, err)
+	assert.Same(t, dual, again)
+	eind[0] = 0
+
+	// Invalidate and SetMesh force a rebuild
+	mp.Invalidate()
+	rebuilt, err := mp.Dual()
+	require.NoError(t, err)
+	assert.NotSame(t, dual, rebuilt)
+	assert.Equal(t, dual.Xadj, rebuilt.Xadj)
+
+	mp.SetMesh(4, 9, eptr[:5], eind[:12])
+	smaller, err := mp.Dual()
+	require.NoError(t, err)
+	assert.Equal(t, 4, smaller.NumVertices())
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				epart, _, err := mp.PartDual(2, nil)
+				assert.NoError(t, err)
+				assert.Len(t, epart, 4)
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestRecommendNcommon(t *testing.T) {
+	assert.Equal(t, int32(1), RecommendNcommon(ElementLine))
+	assert.Equal(t, int32(2), RecommendNcommon(ElementTriangle))
+	assert.Equal(t, int32(2), RecommendNcommon(ElementQuadrilateral))
+	assert.Equal(t, int32(3), RecommendNcommon(ElementTetrahedron))
+	assert.Equal(t, int32(4), RecommendNcommon(ElementHexahedron))
+	assert.Equal(t, int32(3), RecommendNcommon(ElementPrism))
+	assert.Equal(t, int32(3), RecommendNcommon(ElementPyramid))
+
+	// Cube split into 5 tetrahedra: the central tet 4 shares a face with
+	// each corner tet, while corner tets only touch along edges
+	ne := int32(5)
+	nn := int32(8)
+	eptr := []int32{0, 4, 8, 12, 16, 20}
+	eind := []int32{
+		0, 1, 3, 4,
+		1, 2, 3, 6,
+		1, 4, 5, 6,
+		3, 4, 6, 7,
+		1, 3, 4, 6,
+	}
+
+	faceXadj, faceAdjncy, err := MeshToDual(ne, nn, eptr, eind, RecommendNcommon(ElementTetrahedron))
+	require.NoError(t, err)
+	face := NewGraph(faceXadj, faceAdjncy)
+	assert.Equal(t, 4, face.NumEdges())
+	assert.Equal(t, 4, face.Degree(4))
+
+	// Too small an ncommon also links tets that only share an edge
+	edgeXadj, edgeAdjncy, err := MeshToDual(ne, nn, eptr, eind, 2)
+	require.NoError(t, err)
+	edge := NewGraph(edgeXadj, edgeAdjncy)
+	assert.Greater(t, edge.NumEdges(), face.NumEdges())
+
+	// Too large an ncommon disconnects the mesh entirely
+	_, noneAdjncy, err := MeshToDual(ne, nn, eptr, eind, 4)
+	require.NoError(t, err)
+	assert.Empty(t, noneAdjncy)
+}
+
+func TestFindDuplicateElements(t *testing.T) {
+	// Two tetrahedra sharing a face, with the first one duplicated using a
+	// different node order
+	ne := int32(3)
+	eptr := []int32{0, 4, 8, 12}
+	eind := []int32{
+		0, 1, 2, 3,
+		1, 2, 3, 4,
+		3, 2, 0, 1,
+	}
+
+	dups := FindDuplicateElements(ne, eptr, eind)
+	assert.Equal(t, [][]int32{{0, 2}}, dups)
+
+	// Element order of the input is not modified
+	assert.Equal(t, []int32{3, 2, 0, 1}, eind[8:12])
+
+	t.Run("NoDuplicates", func(t *testing.T) {
+		assert.Empty(t, FindDuplicateElements(2, eptr[:3], eind[:8]))
+	})
+
+	t.Run("SubsetIsNotDuplicate", func(t *testing.T) {
+		// A triangle on three of the tet's nodes is a different element
+		eptr := []int32{0, 4, 7, 10}
+		eind := []int32{0, 1, 2, 3, 0, 1, 2, 2, 1, 0}
+		assert.Equal(t, [][]int32{{1, 2}}, FindDuplicateElements(3, eptr, eind))
+	})
+}
+
+func TestConvertMeshToGraphWeights(t *testing.T) {
+	// A 1x8 strip of quads; the first two elements are three times as costly
+	ne, nn := int32(8), int32(18)
+	eptr := make([]int32, ne+1)
+	eind := make([]int32, 0, 4*ne)
+	for e := int32(0); e < ne; e++ {
+		eptr[e+1] = 4 * (e + 1)
+		eind = append(eind, e, e+1, e+10, e+9)
+	}
+	vwgt := []int32{3, 3, 1, 1, 1, 1, 1, 1}
+
+	g, err := ConvertMeshToGraph(ne, nn, eptr, eind, vwgt, true, 2)
+	require.NoError(t, err)
+	require.Equal(t, int(ne), g.NumVertices())
+	assert.Equal(t, vwgt, g.Vwgt)
+
+	// The graph owns its weights
+	vwgt[0] = 7
+	assert.Equal(t, int32(3), g.Vwgt[0])
+	vwgt[0] = 3
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+	part, _, err := PartitionGraph(g, 2, opts)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyBalance(part, g.Vwgt, 2, 100))
+
+	_, err = ConvertMeshToGraph(ne, nn, eptr, eind, vwgt[:4], true, 2)
+	assert.Error(t, err)
+	_, err = ConvertMeshToGraph(ne, nn, eptr, eind, vwgt, false, 2)
+	assert.Error(t, err)
+}
+
+func TestMeshStats(t *testing.T) {
+	// The tetrahedral and hexahedral meshes of examples/mesh
+	tet := MeshStats(5, 8, []int32{0, 4, 8, 12, 16, 20}, []int32{
+		0, 1, 3, 7,
+		1, 2, 3, 7,
+		1, 5, 7, 2,
+		5, 6, 7, 2,
+		1, 5, 7, 4,
+	})
+	assert.Equal(t, MeshStatistics{
+		Elements: 5, Nodes: 8,
+		MinNodesPerElement: 4, MaxNodesPerElement: 4, AvgNodesPerElement: 4,
+		Homogeneous: true,
+	}, tet)
+
+	hexEptr := make([]int32, 9)
+	for i := range hexEptr {
+		hexEptr[i] = int32(i * 8)
+	}
+	hex := MeshStats(8, 27, hexEptr, []int32{
+		0, 1, 4, 3, 9, 10, 13, 12,
+		1, 2, 5, 4, 10, 11, 14, 13,
+		3, 4, 7, 6, 12, 13, 16, 15,
+		4, 5, 8, 7, 13, 14, 17, 16,
+		9, 10, 13, 12, 18, 19, 22, 21,
+		10, 11, 14, 13, 19, 20, 23, 22,
+		12, 13, 16, 15, 21, 22, 25, 24,
+		13, 14, 17, 16, 22, 23, 26, 25,
+	})
+	assert.Equal(t, int32(8), hex.MinNodesPerElement)
+	assert.Equal(t, int32(0), hex.UnreferencedNodes)
+	assert.True(t, hex.Homogeneous)
+
+	// A triangle and a quad over 7 nodes, two of them unused
+	mixed := MeshStats(2, 7, []int32{0, 3, 7}, []int32{0, 1, 2, 1, 3, 4, 2})
+	assert.Equal(t, int32(3), mixed.MinNodesPerElement)
+	assert.Equal(t, int32(4), mixed.MaxNodesPerElement)
+	assert.Equal(t, 3.5, mixed.AvgNodesPerElement)
+	assert.Equal(t, int32(2), mixed.UnreferencedNodes)
+	assert.False(t, mixed.Homogeneous)
+}
+
+func TestMeshToNodalWeighted(t *testing.T) {
+	// Two triangles sharing the edge 1-2, and a third sharing node 2 only
+	//  0---1
+	//  | / |
+	//  2---3
+	//   \
+	//    4---5
+	eptr := []int32{0, 3, 6, 9}
+	eind := []int32{0, 1, 2, 1, 3, 2, 2, 4, 5}
+
+	g, err := MeshToNodalWeighted(3, 6, eptr, eind)
+	require.NoError(t, err)
+	require.Len(t, g.Adjwgt, len(g.Adjncy))
+
+	want := map[[2]int32]int32{
+		{0, 1}: 1, {0, 2}: 1, {1, 2}: 2, {1, 3}: 1, {2, 3}: 1,
+		{2, 4}: 1, {2, 5}: 1, {4, 5}: 1,
+	}
+	got := map[[2]int32]int32{}
+	for v := 0; v < g.NumVertices(); v++ {
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			u := g.Adjncy[j]
+			assert.Equal(t, want[[2]int32{min32(int32(v), u), max32(int32(v), u)}], g.Adjwgt[j],
+				"edge %d-%d", v, u)
+			got[[2]int32{int32(v), u}] = g.Adjwgt[j]
+		}
+	}
+	assert.Len(t, got, 2*len(want))
+
+	_, err = MeshToNodalWeighted(3, 6, eptr, []int32{0, 1, 2, 1, 3, 2, 2, 4, 6})
+	assert.Error(t, err)
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestPartMeshNodalWeights(t *testing.T) {
+	// Strip of 8 triangles over a 2x5 node lattice
+	ne, nn := int32(8), int32(10)
+	eptr := []int32{0, 3, 6, 9, 12, 15, 18, 21, 24}
+	eind := []int32{
+		0, 1, 5, 1, 6, 5,
+		1, 2, 6, 2, 7, 6,
+		2, 3, 7, 3, 8, 7,
+		3, 4, 8, 4, 9, 8,
+	}
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	t.Run("ElementBalanced", func(t *testing.T) {
+		// PartMeshDual weighs elements, PartMeshNodal weighs nodes
+		_, _, _, err := PartMeshDual(ne, nn, eptr, eind, make([]int32, ne), nil, 2, 2, nil, opts)
+		assert.NoError(t, err)
+		_, _, _, err = PartMeshDual(ne, nn, eptr, eind, make([]int32, nn), nil, 2, 2, nil, opts)
+		assert.ErrorContains(t, err, "want ne = 8")
+
+		nodeWeights := []int32{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+		_, epart, npart, err := PartMeshNodal(ne, nn, eptr, eind, nodeWeights, nil, 2, nil, opts)
+		require.NoError(t, err)
+		assert.Len(t, epart, int(ne))
+		assert.Len(t, npart, int(nn))
+		_, _, _, err = PartMeshNodal(ne, nn, eptr, eind, make([]int32, ne), nil, 2, nil, opts)
+		assert.ErrorContains(t, err, "want nn = 10")
+	})
+
+	t.Run("NodeBalanced", func(t *testing.T) {
+		nodeWeights := []int32{3, 1, 1, 1, 3, 3, 1, 1, 1, 3}
+		objval, epart, npart, err := PartMeshNodalWeightedNodes(ne, nn, eptr, eind, nodeWeights, 2, opts)
+		require.NoError(t, err)
+		require.Len(t, npart, int(nn))
+		require.Len(t, epart, int(ne))
+
+		g, err := MeshToNodalWeighted(ne, nn, eptr, eind)
+		require.NoError(t, err)
+		assert.Equal(t, CalculateEdgeCut(g, npart), objval)
+
+		// Every element follows the majority of its nodes
+		for e := int32(0); e < ne; e++ {
+			votes := 0
+			for _, n := range eind[eptr[e]:eptr[e+1]] {
+				if npart[n] == epart[e] {
+					votes++
+				}
+			}
+			assert.GreaterOrEqual(t, votes, 2, "element %d", e)
+		}
+
+		_, _, _, err = PartMeshNodalWeightedNodes(ne, nn, eptr, eind, nodeWeights[:4], 2, opts)
+		assert.Error(t, err)
+	})
+}
+
+func TestMeshElementFaceCounts(t *testing.T) {
+	// 2x2 quadrilaterals over a 3x3 node lattice
+	eptr := []int32{0, 4, 8, 12, 16}
+	eind := []int32{0, 1, 4, 3, 1, 2, 5, 4, 3, 4, 7, 6, 4, 5, 8, 7}
+
+	// Each quad shares an edge with two others and a corner with the third
+	counts, err := MeshElementFaceCounts(4, 9, eptr, eind, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{2, 2, 2, 2}, counts)
+	counts, err = MeshElementFaceCounts(4, 9, eptr, eind, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{3, 3, 3, 3}, counts)
+
+	// The ends of a triangle strip have a single interface face
+	counts, err = MeshElementFaceCounts(4, 6, []int32{0, 3, 6, 9, 12},
+		[]int32{0, 1, 3, 1, 4, 3, 1, 2, 4, 2, 5, 4}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2, 2, 1}, counts)
+
+	_, err = MeshElementFaceCounts(4, 5, eptr, eind, 2)
+	assert.Error(t, err)
+}
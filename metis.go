@@ -7,12 +7,14 @@ package metis
 #cgo darwin CFLAGS: -I/opt/homebrew/include -I/usr/local/include
 #cgo darwin LDFLAGS: -L/opt/homebrew/lib -L/usr/local/lib -lmetis -lm
 
+#include <stdlib.h>
 #include <metis.h>
 */
 import "C"
 import (
 	"errors"
 	"fmt"
+	"math"
 	"unsafe"
 )
 
@@ -59,24 +61,37 @@ const (
 )
 
 // Partitioning types
+//
+// Deprecated: Use the typed MethodRB and MethodKway, stored with SetPType. The
+// untyped constants remain so that existing options arrays keep compiling.
 const (
 	PTypeRB   = C.METIS_PTYPE_RB
 	PTypeKway = C.METIS_PTYPE_KWAY
 )
 
+// GType selects the graph PartMesh partitions a mesh through: GraphDual or
+// GraphNodal
+type GType int32
+
 // Graph types
+//
+// Deprecated: Use the typed GraphDual and GraphNodal.
 const (
 	GTypeDual  = C.METIS_GTYPE_DUAL
 	GTypeNodal = C.METIS_GTYPE_NODAL
 )
 
 // Coarsening types
+//
+// Deprecated: Use the typed CoarsenRM and CoarsenSHEM, stored with SetCType.
 const (
 	CTypeRM   = C.METIS_CTYPE_RM
 	CTypeSHEM = C.METIS_CTYPE_SHEM
 )
 
 // Initial partitioning types
+//
+// Deprecated: Use the typed Initial values, stored with SetIPType.
 const (
 	IPTypeGrow    = C.METIS_IPTYPE_GROW
 	IPTypeRandom  = C.METIS_IPTYPE_RANDOM
@@ -86,6 +101,8 @@ const (
 )
 
 // Refinement types
+//
+// Deprecated: Use the typed Refine values, stored with SetRType.
 const (
 	RTypeFM        = C.METIS_RTYPE_FM
 	RTypeGreedy    = C.METIS_RTYPE_GREEDY
@@ -94,12 +111,60 @@ const (
 )
 
 // Objective types
+//
+// Deprecated: Use the typed Objective values, stored with SetObjType.
 const (
 	ObjTypeCut  = C.METIS_OBJTYPE_CUT
 	ObjTypeVol  = C.METIS_OBJTYPE_VOL
 	ObjTypeNode = C.METIS_OBJTYPE_NODE
 )
 
+// The enumerated option values are typed below, which gives them a String
+// method and lets the setters SetPType, SetCType, SetIPType, SetRType,
+// SetObjType and SetGType reject a value of the wrong option at compile time.
+
+// PType is a partitioning method, the value of OptionPType
+type PType int32
+
+// CType is a coarsening scheme, the value of OptionCType
+type CType int32
+
+// IPType is an initial partitioning algorithm, the value of OptionIPType
+type IPType int32
+
+// RType is a refinement algorithm, the value of OptionRType
+type RType int32
+
+// ObjType is a partitioning objective, the value of OptionObjType
+type ObjType int32
+
+// Option values
+const (
+	MethodRB   PType = PTypeRB
+	MethodKway PType = PTypeKway
+
+	GraphDual  GType = GTypeDual
+	GraphNodal GType = GTypeNodal
+
+	CoarsenRM   CType = CTypeRM
+	CoarsenSHEM CType = CTypeSHEM
+
+	InitialGrow    IPType = IPTypeGrow
+	InitialRandom  IPType = IPTypeRandom
+	InitialEdge    IPType = IPTypeEdge
+	InitialNode    IPType = IPTypeNode
+	InitialMetisRB IPType = IPTypeMetisRB
+
+	RefineFM        RType = RTypeFM
+	RefineGreedy    RType = RTypeGreedy
+	RefineSep2Sided RType = RTypeSep2Sided
+	RefineSep1Sided RType = RTypeSep1Sided
+
+	ObjectiveCut  ObjType = ObjTypeCut
+	ObjectiveVol  ObjType = ObjTypeVol
+	ObjectiveNode ObjType = ObjTypeNode
+)
+
 // Debug levels
 const (
 	DBGInfo       = C.METIS_DBG_INFO
@@ -127,137 +192,275 @@ func SetDefaultOptions(opts []int32) error {
 	return nil
 }
 
-// PartGraphRecursive partitions a graph using multilevel recursive bisection
-func PartGraphRecursive(xadj, adjncy []int32, nparts int32, options []int32) ([]int32, int32, error) {
-	nvtxs := int32(len(xadj) - 1)
-	ncon := int32(1)
-	part := make([]int32, nvtxs)
-	var objval C.idx_t
+// Default load imbalance tolerances used by METIS when OptionUFactor is -1
+const (
+	DefaultImbalanceKway      = 1.03
+	DefaultImbalanceRecursive = 1.001
+)
 
-	var opts *C.idx_t
-	if options != nil && len(options) == NoOptions {
-		opts = (*C.idx_t)(unsafe.Pointer(&options[0]))
+// SetImbalance sets OptionUFactor from a maximum load imbalance ratio, where
+// 1.03 allows the heaviest partition to be 3% above the average. METIS stores
+// this as an integer scaled by 1/1000, ufactor = (maxImbalance-1)*1000, so
+// 1.03 becomes 30 and 1.001 becomes 1.
+func SetImbalance(options []int32, maxImbalance float64) error {
+	if len(options) != NoOptions {
+		return fmt.Errorf("options array must have %d elements", NoOptions)
 	}
-
-	ret := C.METIS_PartGraphRecursive(
-		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
-		(*C.idx_t)(unsafe.Pointer(&ncon)),
-		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
-		nil, nil, nil,
-		(*C.idx_t)(unsafe.Pointer(&nparts)),
-		nil, nil,
-		opts,
-		&objval,
-		(*C.idx_t)(unsafe.Pointer(&part[0])),
-	)
-
-	if ret != OK {
-		return nil, 0, getError(ret)
+	if maxImbalance < 1 {
+		return fmt.Errorf("imbalance must be at least 1.0, got %g", maxImbalance)
 	}
 
-	return part, int32(objval), nil
+	options[OptionUFactor] = int32(math.Round((maxImbalance - 1) * 1000))
+	return nil
 }
 
-// PartGraphKway partitions a graph using multilevel k-way partitioning
-func PartGraphKway(xadj, adjncy []int32, nparts int32, options []int32) ([]int32, int32, error) {
-	nvtxs := int32(len(xadj) - 1)
-	ncon := int32(1)
-	part := make([]int32, nvtxs)
-	var objval C.idx_t
+// GetImbalance returns the maximum load imbalance ratio encoded by
+// OptionUFactor. When the option is left at its default, the METIS default for
+// the selected method is returned: 1.03 for k-way and 1.001 for recursive
+// bisection.
+func GetImbalance(options []int32) float64 {
+	if len(options) != NoOptions || options[OptionUFactor] < 0 {
+		if len(options) == NoOptions && PType(options[OptionPType]) == MethodRB {
+			return DefaultImbalanceRecursive
+		}
+		return DefaultImbalanceKway
+	}
+	return 1 + float64(options[OptionUFactor])/1000
+}
 
-	var opts *C.idx_t
-	if options != nil && len(options) == NoOptions {
-		opts = (*C.idx_t)(unsafe.Pointer(&options[0]))
+// ValidateGraph checks the structure of a CSR graph before it is handed to
+// METIS. METIS trusts its input, and malformed arrays make it read out of
+// bounds and crash the whole process rather than return an error. The checks
+// performed are:
+//   - xadj has at least two entries (one vertex) and starts at 0
+//   - xadj is non-decreasing and xadj[nvtxs] equals len(adjncy)
+//   - every adjncy entry is a vertex id in [0, nvtxs)
+//
+// Every graph partitioning and ordering function runs these checks first.
+// They do not cover inputs that are structurally sound but rejected or
+// mishandled inside METIS; see PartitionSubprocess for isolating those.
+func ValidateGraph(xadj, adjncy []int32) error {
+	if len(xadj) < 2 {
+		return fmt.Errorf("xadj must have at least 2 elements, got %d", len(xadj))
+	}
+	if xadj[0] != 0 {
+		return fmt.Errorf("xadj[0] must be 0, got %d", xadj[0])
 	}
 
-	ret := C.METIS_PartGraphKway(
-		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
-		(*C.idx_t)(unsafe.Pointer(&ncon)),
-		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
-		nil, nil, nil,
-		(*C.idx_t)(unsafe.Pointer(&nparts)),
-		nil, nil,
-		opts,
-		&objval,
-		(*C.idx_t)(unsafe.Pointer(&part[0])),
-	)
+	nvtxs := int32(len(xadj) - 1)
+	for i := int32(0); i < nvtxs; i++ {
+		if xadj[i+1] < xadj[i] {
+			return fmt.Errorf("xadj is decreasing at vertex %d (%d > %d)", i, xadj[i], xadj[i+1])
+		}
+	}
+	if int(xadj[nvtxs]) != len(adjncy) {
+		return fmt.Errorf("xadj[%d] = %d does not match adjncy length %d", nvtxs, xadj[nvtxs], len(adjncy))
+	}
 
-	if ret != OK {
-		return nil, 0, getError(ret)
+	for j, v := range adjncy {
+		if v < 0 || v >= nvtxs {
+			return fmt.Errorf("adjncy index %d holds vertex %d, outside [0, %d)", j, v, nvtxs)
+		}
 	}
 
-	return part, int32(objval), nil
+	return nil
 }
 
-// PartGraphRecursiveWeighted partitions a graph with vertex and edge weights using recursive bisection
-func PartGraphRecursiveWeighted(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpwgts, ubvec []float32, options []int32) ([]int32, int32, error) {
-	nvtxs := int32(len(xadj) - 1)
-	ncon := int32(1)
-	if vwgt != nil && len(vwgt) != int(nvtxs) {
-		return nil, 0, errors.New("vwgt length must equal number of vertices")
+// ValidateMesh checks that eptr and eind describe a well-formed mesh of ne
+// elements over nn nodes. Like ValidateGraph, it guards against inputs that
+// make METIS index out of bounds and crash the process. The checks performed
+// are:
+//   - ne and nn are positive and eptr has ne+1 entries
+//   - eptr starts at 0, is non-decreasing and eptr[ne] equals len(eind)
+//   - every eind entry is a node id in [0, nn)
+//
+// The mesh conversion and partitioning functions run these checks on every
+// call before calling METIS; they are a single pass over eind, small next to
+// the METIS call itself.
+func ValidateMesh(ne, nn int32, eptr, eind []int32) error {
+	if ne < 1 {
+		return fmt.Errorf("ne must be at least 1, got %d", ne)
 	}
-	if adjwgt != nil && len(adjwgt) != len(adjncy) {
-		return nil, 0, errors.New("adjwgt length must equal adjncy length")
+	if nn < 1 {
+		return fmt.Errorf("nn must be at least 1, got %d", nn)
+	}
+	if len(eptr) != int(ne)+1 {
+		return fmt.Errorf("eptr must have ne+1 = %d elements, got %d", ne+1, len(eptr))
+	}
+	if eptr[0] != 0 {
+		return fmt.Errorf("eptr[0] must be 0, got %d", eptr[0])
 	}
 
-	part := make([]int32, nvtxs)
-	var objval C.idx_t
-
-	var vwgtPtr, adjwgtPtr *C.idx_t
-	if vwgt != nil {
-		vwgtPtr = (*C.idx_t)(unsafe.Pointer(&vwgt[0]))
+	for e := int32(0); e < ne; e++ {
+		if eptr[e+1] < eptr[e] {
+			return fmt.Errorf("eptr is decreasing at element %d (%d > %d)", e, eptr[e], eptr[e+1])
+		}
 	}
-	if adjwgt != nil {
-		adjwgtPtr = (*C.idx_t)(unsafe.Pointer(&adjwgt[0]))
+	if int(eptr[ne]) != len(eind) {
+		return fmt.Errorf("eptr[%d] = %d does not match eind length %d", ne, eptr[ne], len(eind))
 	}
 
-	var tpwgtsPtr, ubvecPtr *C.real_t
-	if tpwgts != nil {
-		tpwgtsPtr = (*C.real_t)(unsafe.Pointer(&tpwgts[0]))
+	for j, n := range eind {
+		if n < 0 || n >= nn {
+			return fmt.Errorf("eind index %d holds node %d, outside [0, %d)", j, n, nn)
+		}
 	}
-	if ubvec != nil {
-		ubvecPtr = (*C.real_t)(unsafe.Pointer(&ubvec[0]))
+
+	return nil
+}
+
+// ValidateEdgeWeights checks that adjwgt holds one weight per adjncy entry and
+// that every edge has the same weight in both directions, naming the first
+// offending edge. METIS accepts asymmetric weights but the cut it optimizes is
+// then biased. The check costs a hash map over all edges, so the partitioning
+// functions only run it when asked, through WithEdgeWeightCheck or
+// Partitioner.CheckEdgeWeights; callers of PartGraphKwayWeighted call it
+// directly. SymmetrizeEdgeWeights repairs weights it rejects.
+func ValidateEdgeWeights(xadj, adjncy, adjwgt []int32) error {
+	if err := ValidateGraph(xadj, adjncy); err != nil {
+		return err
 	}
+	if len(adjwgt) != len(adjncy) {
+		return fmt.Errorf("adjwgt must have %d elements, got %d", len(adjncy), len(adjwgt))
+	}
+	return checkEdgeWeightSymmetry(xadj, adjncy, adjwgt)
+}
 
-	var opts *C.idx_t
-	if options != nil && len(options) == NoOptions {
-		opts = (*C.idx_t)(unsafe.Pointer(&options[0]))
+// checkEdgeWeightSymmetry returns an error describing the first edge whose
+// weight differs from, or lacks, its reverse direction
+func checkEdgeWeightSymmetry(xadj, adjncy, adjwgt []int32) error {
+	index := edgeIndex(xadj, adjncy)
+	for i := 0; i+1 < len(xadj); i++ {
+		for j := xadj[i]; j < xadj[i+1]; j++ {
+			v := adjncy[j]
+			r, ok := index[[2]int32{v, int32(i)}]
+			if !ok {
+				return fmt.Errorf("edge %d->%d has no reverse edge %d->%d", i, v, v, i)
+			}
+			if adjwgt[j] != adjwgt[r] {
+				return fmt.Errorf("asymmetric edge weight: %d->%d has weight %d but %d->%d has weight %d",
+					i, v, adjwgt[j], v, i, adjwgt[r])
+			}
+		}
 	}
+	return nil
+}
 
-	ret := C.METIS_PartGraphRecursive(
-		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
-		(*C.idx_t)(unsafe.Pointer(&ncon)),
-		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
-		vwgtPtr, nil, adjwgtPtr,
-		(*C.idx_t)(unsafe.Pointer(&nparts)),
-		tpwgtsPtr, ubvecPtr,
-		opts,
-		&objval,
-		(*C.idx_t)(unsafe.Pointer(&part[0])),
-	)
+// SetCompress enables or disables graph compression in NodeND. With
+// compression, vertices with identical adjacency lists (common in matrices
+// from multi-DOF discretizations) are merged before ordering and numbered
+// consecutively afterwards, which makes ordering faster and often better.
+// METIS compresses by default.
+func SetCompress(options []int32, enable bool) error {
+	return setBoolOption(options, OptionCompress, enable)
+}
 
-	if ret != OK {
-		return nil, 0, getError(ret)
+// SetCCOrder enables or disables ordering each connected component separately
+// in NodeND. This helps on block-diagonal matrices, whose components would
+// otherwise be dissected together. METIS leaves it off by default.
+func SetCCOrder(options []int32, enable bool) error {
+	return setBoolOption(options, OptionCCOrder, enable)
+}
+
+// setBoolOption stores a 0/1 flag in the options array
+func setBoolOption(options []int32, option int, enable bool) error {
+	if len(options) != NoOptions {
+		return fmt.Errorf("options array must have %d elements", NoOptions)
+	}
+
+	options[option] = 0
+	if enable {
+		options[option] = 1
 	}
+	return nil
+}
+
+// PartGraphRecursive partitions a graph using multilevel recursive bisection
+func PartGraphRecursive(xadj, adjncy []int32, nparts int32, options []int32) ([]int32, int32, error) {
+	return PartGraphRecursiveWeighted(xadj, adjncy, nil, nil, nparts, nil, nil, options)
+}
+
+// PartGraphKway partitions a graph using multilevel k-way partitioning
+func PartGraphKway(xadj, adjncy []int32, nparts int32, options []int32) ([]int32, int32, error) {
+	return PartGraphKwayWeighted(xadj, adjncy, nil, nil, nparts, nil, nil, options)
+}
 
-	return part, int32(objval), nil
+// PartGraphRecursiveWeighted partitions a graph with vertex and edge weights using recursive bisection.
+// tpwgts, if non-nil, needs one target weight per partition and ubvec at most
+// one imbalance tolerance, as only single-constraint graphs are supported.
+func PartGraphRecursiveWeighted(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpwgts, ubvec []float32, options []int32) ([]int32, int32, error) {
+	part := make([]int32, numVertices(xadj))
+	objval, err := partGraph(true, xadj, adjncy, vwgt, adjwgt, nparts, tpwgts, ubvec, options, part)
+	if err != nil {
+		return nil, 0, err
+	}
+	return part, objval, nil
 }
 
-// PartGraphKwayWeighted partitions a graph with vertex and edge weights using k-way partitioning
+// PartGraphKwayWeighted partitions a graph with vertex and edge weights using k-way partitioning.
+// tpwgts, if non-nil, needs one target weight per partition and ubvec at most
+// one imbalance tolerance, as only single-constraint graphs are supported.
 func PartGraphKwayWeighted(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpwgts, ubvec []float32, options []int32) ([]int32, int32, error) {
+	part := make([]int32, numVertices(xadj))
+	objval, err := partGraph(false, xadj, adjncy, vwgt, adjwgt, nparts, tpwgts, ubvec, options, part)
+	if err != nil {
+		return nil, 0, err
+	}
+	return part, objval, nil
+}
+
+// numVertices returns the number of vertices described by xadj, or 0 when
+// xadj is too short to describe any
+func numVertices(xadj []int32) int {
+	if len(xadj) < 1 {
+		return 0
+	}
+	return len(xadj) - 1
+}
+
+// checkTargetWeights checks that tpwgts, if given, holds nparts*ncon entries
+// and ubvec ncon. METIS reads exactly that many values whatever the slice
+// length, so a short slice would make it read past the end. An empty ubvec
+// means none, like nil.
+func checkTargetWeights(tpwgts, ubvec []float32, nparts, ncon int32) error {
+	if tpwgts != nil && len(tpwgts) != int(nparts)*int(ncon) {
+		return fmt.Errorf("tpwgts must have nparts*ncon = %d elements, got %d", int(nparts)*int(ncon), len(tpwgts))
+	}
+	if len(ubvec) > 0 && len(ubvec) != int(ncon) {
+		return fmt.Errorf("ubvec must have ncon = %d elements, got %d", ncon, len(ubvec))
+	}
+	return nil
+}
+
+// partGraph validates its input and runs recursive bisection or k-way
+// partitioning, writing the partition into part (one entry per vertex)
+func partGraph(recursive bool, xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpwgts, ubvec []float32, options []int32, part []int32) (int32, error) {
+	if err := ValidateGraph(xadj, adjncy); err != nil {
+		return 0, err
+	}
+
 	nvtxs := int32(len(xadj) - 1)
 	ncon := int32(1)
 	if vwgt != nil && len(vwgt) != int(nvtxs) {
-		return nil, 0, errors.New("vwgt length must equal number of vertices")
+		return 0, errors.New("vwgt length must equal number of vertices")
 	}
 	if adjwgt != nil && len(adjwgt) != len(adjncy) {
-		return nil, 0, errors.New("adjwgt length must equal adjncy length")
+		return 0, errors.New("adjwgt length must equal adjncy length")
+	}
+	if len(part) != int(nvtxs) {
+		return 0, errors.New("part length must equal number of vertices")
+	}
+	if err := checkTargetWeights(tpwgts, ubvec, nparts, ncon); err != nil {
+		return 0, err
+	}
+
+	// With one vertex per partition and equal targets there is nothing to
+	// optimize, and METIS does not reliably return the only possible answer,
+	// so answer directly. Explicit target weights are left to METIS.
+	if nparts == nvtxs && tpwgts == nil {
+		return singletonPartition(recursive, xadj, adjncy, vwgt, adjwgt, options, part), nil
 	}
 
-	part := make([]int32, nvtxs)
 	var objval C.idx_t
 
 	var vwgtPtr, adjwgtPtr *C.idx_t
@@ -265,14 +468,14 @@ func PartGraphKwayWeighted(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpw
 		vwgtPtr = (*C.idx_t)(unsafe.Pointer(&vwgt[0]))
 	}
 	if adjwgt != nil {
-		adjwgtPtr = (*C.idx_t)(unsafe.Pointer(&adjwgt[0]))
+		adjwgtPtr = idxPtr(adjwgt)
 	}
 
 	var tpwgtsPtr, ubvecPtr *C.real_t
 	if tpwgts != nil {
 		tpwgtsPtr = (*C.real_t)(unsafe.Pointer(&tpwgts[0]))
 	}
-	if ubvec != nil {
+	if len(ubvec) > 0 {
 		ubvecPtr = (*C.real_t)(unsafe.Pointer(&ubvec[0]))
 	}
 
@@ -281,28 +484,67 @@ func PartGraphKwayWeighted(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpw
 		opts = (*C.idx_t)(unsafe.Pointer(&options[0]))
 	}
 
-	ret := C.METIS_PartGraphKway(
-		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
-		(*C.idx_t)(unsafe.Pointer(&ncon)),
-		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
-		vwgtPtr, nil, adjwgtPtr,
-		(*C.idx_t)(unsafe.Pointer(&nparts)),
-		tpwgtsPtr, ubvecPtr,
-		opts,
-		&objval,
-		(*C.idx_t)(unsafe.Pointer(&part[0])),
-	)
+	var ret C.int
+	if recursive {
+		ret = C.METIS_PartGraphRecursive(
+			(*C.idx_t)(unsafe.Pointer(&nvtxs)),
+			(*C.idx_t)(unsafe.Pointer(&ncon)),
+			(*C.idx_t)(unsafe.Pointer(&xadj[0])),
+			idxPtr(adjncy),
+			vwgtPtr, nil, adjwgtPtr,
+			(*C.idx_t)(unsafe.Pointer(&nparts)),
+			tpwgtsPtr, ubvecPtr,
+			opts,
+			&objval,
+			(*C.idx_t)(unsafe.Pointer(&part[0])),
+		)
+	} else {
+		ret = C.METIS_PartGraphKway(
+			(*C.idx_t)(unsafe.Pointer(&nvtxs)),
+			(*C.idx_t)(unsafe.Pointer(&ncon)),
+			(*C.idx_t)(unsafe.Pointer(&xadj[0])),
+			idxPtr(adjncy),
+			vwgtPtr, nil, adjwgtPtr,
+			(*C.idx_t)(unsafe.Pointer(&nparts)),
+			tpwgtsPtr, ubvecPtr,
+			opts,
+			&objval,
+			(*C.idx_t)(unsafe.Pointer(&part[0])),
+		)
+	}
 
+	if ret == ErrorInput {
+		return 0, diagnoseGraphInput(xadj, adjncy, vwgt, adjwgt, nparts, tpwgts, ubvec)
+	}
 	if ret != OK {
-		return nil, 0, getError(ret)
+		return 0, getError(ret)
 	}
 
-	return part, int32(objval), nil
+	return int32(objval), nil
+}
+
+// singletonPartition fills part with the identity partition, each vertex on
+// its own, and returns its objective value: every edge is cut, so this is the
+// total edge weight, or the communication volume when k-way partitioning
+// minimizes volume
+func singletonPartition(recursive bool, xadj, adjncy, vwgt, adjwgt, options, part []int32) int32 {
+	for i := range part {
+		part[i] = int32(i)
+	}
+
+	m := Metrics(&Graph{Xadj: xadj, Adjncy: adjncy, Vwgt: vwgt, Adjwgt: adjwgt}, part, int32(len(part)))
+	if !recursive && len(options) == NoOptions && ObjType(options[OptionObjType]) == ObjectiveVol {
+		return m.CommVolume
+	}
+	return m.EdgeCut
 }
 
 // MeshToDual converts a mesh to its dual graph
 func MeshToDual(ne, nn int32, eptr, eind []int32, ncommon int32) ([]int32, []int32, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return nil, nil, err
+	}
+
 	var xadj, adjncy *C.idx_t
 	var numflag C.idx_t = 0 // C-style numbering
 
@@ -343,6 +585,10 @@ func MeshToDual(ne, nn int32, eptr, eind []int32, ncommon int32) ([]int32, []int
 
 // MeshToNodal converts a mesh to its nodal graph
 func MeshToNodal(ne, nn int32, eptr, eind []int32) ([]int32, []int32, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return nil, nil, err
+	}
+
 	var xadj, adjncy *C.idx_t
 	var numflag C.idx_t = 0 // C-style numbering
 
@@ -380,8 +626,20 @@ func MeshToNodal(ne, nn int32, eptr, eind []int32) ([]int32, []int32, error) {
 	return xadjSlice, adjncySlice, nil
 }
 
-// PartMeshNodal partitions a mesh using its nodal graph
+// PartMeshNodal partitions a mesh using its nodal graph. The partitioning
+// balances nodes: vwgt and vsize, when given, hold one weight and size per
+// node (nn entries), not per element. The element partition is derived from
+// the node partition afterwards. Use PartMeshNodalWeightedNodes to also weight
+// the edges between nodes by the elements they share.
 func PartMeshNodal(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, nparts int32, tpwgts []float32, options []int32) (int32, []int32, []int32, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if err := checkMeshWeights(vwgt, vsize, nn, "nn"); err != nil {
+		return 0, nil, nil, err
+	}
+
 	var objval C.idx_t
 	epart := make([]int32, ne)
 	npart := make([]int32, nn)
@@ -394,6 +652,10 @@ func PartMeshNodal(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, nparts
 		vsizePtr = (*C.idx_t)(unsafe.Pointer(&vsize[0]))
 	}
 
+	if err := checkTargetWeights(tpwgts, nil, nparts, 1); err != nil {
+		return 0, nil, nil, err
+	}
+
 	var tpwgtsPtr *C.real_t
 	if tpwgts != nil {
 		tpwgtsPtr = (*C.real_t)(unsafe.Pointer(&tpwgts[0]))
@@ -425,8 +687,19 @@ func PartMeshNodal(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, nparts
 	return int32(objval), epart, npart, nil
 }
 
-// PartMeshDual partitions a mesh using its dual graph
+// PartMeshDual partitions a mesh using its dual graph. The partitioning
+// balances elements: vwgt and vsize, when given, hold one weight and size per
+// element (ne entries). The node partition is derived from the element
+// partition afterwards.
 func PartMeshDual(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, ncommon, nparts int32, tpwgts []float32, options []int32) (int32, []int32, []int32, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if err := checkMeshWeights(vwgt, vsize, ne, "ne"); err != nil {
+		return 0, nil, nil, err
+	}
+
 	var objval C.idx_t
 	epart := make([]int32, ne)
 	npart := make([]int32, nn)
@@ -439,6 +712,10 @@ func PartMeshDual(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, ncommon
 		vsizePtr = (*C.idx_t)(unsafe.Pointer(&vsize[0]))
 	}
 
+	if err := checkTargetWeights(tpwgts, nil, nparts, 1); err != nil {
+		return 0, nil, nil, err
+	}
+
 	var tpwgtsPtr *C.real_t
 	if tpwgts != nil {
 		tpwgtsPtr = (*C.real_t)(unsafe.Pointer(&tpwgts[0]))
@@ -471,8 +748,113 @@ func PartMeshDual(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, ncommon
 	return int32(objval), epart, npart, nil
 }
 
+// PartMeshDualElemOnly partitions a mesh using its dual graph and returns only
+// the element partition. METIS_PartMeshDual always derives a node partition as
+// well, so it still runs, but the size-nn node array lives in a C scratch
+// buffer that is released before returning instead of being kept as a Go
+// slice. Use it for large meshes when node assignments are never needed;
+// otherwise PartMeshDual costs the same and returns both.
+func PartMeshDualElemOnly(ne, nn int32, eptr, eind []int32, vwgt, vsize []int32, ncommon, nparts int32, tpwgts []float32, options []int32) (int32, []int32, error) {
+	if err := ValidateMesh(ne, nn, eptr, eind); err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkMeshWeights(vwgt, vsize, ne, "ne"); err != nil {
+		return 0, nil, err
+	}
+
+	var objval C.idx_t
+	epart := make([]int32, ne)
+
+	npart := (*C.idx_t)(C.malloc(C.size_t(nn+1) * C.size_t(unsafe.Sizeof(C.idx_t(0)))))
+	if npart == nil {
+		return 0, nil, ErrMemory
+	}
+	defer C.free(unsafe.Pointer(npart))
+
+	var vwgtPtr, vsizePtr *C.idx_t
+	if vwgt != nil {
+		vwgtPtr = (*C.idx_t)(unsafe.Pointer(&vwgt[0]))
+	}
+	if vsize != nil {
+		vsizePtr = (*C.idx_t)(unsafe.Pointer(&vsize[0]))
+	}
+
+	if err := checkTargetWeights(tpwgts, nil, nparts, 1); err != nil {
+		return 0, nil, err
+	}
+
+	var tpwgtsPtr *C.real_t
+	if tpwgts != nil {
+		tpwgtsPtr = (*C.real_t)(unsafe.Pointer(&tpwgts[0]))
+	}
+
+	var opts *C.idx_t
+	if options != nil && len(options) == NoOptions {
+		opts = (*C.idx_t)(unsafe.Pointer(&options[0]))
+	}
+
+	ret := C.METIS_PartMeshDual(
+		(*C.idx_t)(unsafe.Pointer(&ne)),
+		(*C.idx_t)(unsafe.Pointer(&nn)),
+		(*C.idx_t)(unsafe.Pointer(&eptr[0])),
+		(*C.idx_t)(unsafe.Pointer(&eind[0])),
+		vwgtPtr, vsizePtr,
+		(*C.idx_t)(unsafe.Pointer(&ncommon)),
+		(*C.idx_t)(unsafe.Pointer(&nparts)),
+		tpwgtsPtr,
+		opts,
+		&objval,
+		(*C.idx_t)(unsafe.Pointer(&epart[0])),
+		npart,
+	)
+
+	if ret != OK {
+		return 0, nil, getError(ret)
+	}
+
+	return int32(objval), epart, nil
+}
+
+// checkMeshWeights checks that the mesh vertex weights and sizes, when given,
+// have one entry per partitioned item, n of them, named by what
+func checkMeshWeights(vwgt, vsize []int32, n int32, what string) error {
+	if vwgt != nil && len(vwgt) != int(n) {
+		return fmt.Errorf("vwgt has %d entries, want %s = %d", len(vwgt), what, n)
+	}
+	if vsize != nil && len(vsize) != int(n) {
+		return fmt.Errorf("vsize has %d entries, want %s = %d", len(vsize), what, n)
+	}
+	return nil
+}
+
+// PartMesh partitions a mesh through the graph selected by gtype, calling
+// PartMeshDual for GraphDual and PartMeshNodal for GraphNodal; ncommon is only
+// used by the dual graph. It returns the objective value and the element and
+// node partitions.
+//
+// The dual graph connects elements sharing at least ncommon nodes, so it
+// balances elements and cuts faces: use it when the work lives on the
+// elements, as in finite volume and most finite element assembly. The nodal
+// graph connects nodes sharing an element and balances nodes: use it when the
+// unknowns live on the nodes, as in nodal finite element solvers, or when
+// elements are so mixed that no single ncommon fits.
+func PartMesh(ne, nn int32, eptr, eind []int32, gtype GType, ncommon, nparts int32, vwgt, vsize []int32, tpwgts []float32, options []int32) (int32, []int32, []int32, error) {
+	switch gtype {
+	case GraphDual:
+		return PartMeshDual(ne, nn, eptr, eind, vwgt, vsize, ncommon, nparts, tpwgts, options)
+	case GraphNodal:
+		return PartMeshNodal(ne, nn, eptr, eind, vwgt, vsize, nparts, tpwgts, options)
+	}
+	return 0, nil, nil, fmt.Errorf("invalid graph type %d, want GraphDual or GraphNodal", gtype)
+}
+
 // NodeND computes fill reducing ordering using nested dissection
 func NodeND(xadj, adjncy, vwgt []int32, options []int32) ([]int32, []int32, error) {
+	if err := ValidateGraph(xadj, adjncy); err != nil {
+		return nil, nil, err
+	}
+
 	nvtxs := int32(len(xadj) - 1)
 	perm := make([]int32, nvtxs)
 	iperm := make([]int32, nvtxs)
@@ -490,7 +872,7 @@ func NodeND(xadj, adjncy, vwgt []int32, options []int32) ([]int32, []int32, erro
 	ret := C.METIS_NodeND(
 		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
 		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
+		idxPtr(adjncy),
 		vwgtPtr,
 		opts,
 		(*C.idx_t)(unsafe.Pointer(&perm[0])),
@@ -504,8 +886,24 @@ func NodeND(xadj, adjncy, vwgt []int32, options []int32) ([]int32, []int32, erro
 	return perm, iperm, nil
 }
 
+// NodeNDBase computes the same ordering as NodeND but returns perm and iperm
+// numbered from base, 1 for Fortran solvers. The input graph stays 0-based.
+func NodeNDBase(xadj, adjncy, vwgt []int32, options []int32, base int32) ([]int32, []int32, error) {
+	perm, iperm, err := NodeND(xadj, adjncy, vwgt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	ShiftBase(perm, base)
+	ShiftBase(iperm, base)
+	return perm, iperm, nil
+}
+
 // ComputeVertexSeparator computes a vertex separator from an edge separator
 func ComputeVertexSeparator(xadj, adjncy, vwgt []int32, options []int32) (int32, []int32, error) {
+	if err := ValidateGraph(xadj, adjncy); err != nil {
+		return 0, nil, err
+	}
+
 	nvtxs := int32(len(xadj) - 1)
 	part := make([]int32, nvtxs)
 	var sepsize C.idx_t
@@ -523,7 +921,7 @@ func ComputeVertexSeparator(xadj, adjncy, vwgt []int32, options []int32) (int32,
 	ret := C.METIS_ComputeVertexSeparator(
 		(*C.idx_t)(unsafe.Pointer(&nvtxs)),
 		(*C.idx_t)(unsafe.Pointer(&xadj[0])),
-		(*C.idx_t)(unsafe.Pointer(&adjncy[0])),
+		idxPtr(adjncy),
 		vwgtPtr,
 		opts,
 		&sepsize,
@@ -537,15 +935,93 @@ func ComputeVertexSeparator(xadj, adjncy, vwgt []int32, options []int32) (int32,
 	return int32(sepsize), part, nil
 }
 
+// idxPtr returns a C pointer to the first element of s, or nil when s is
+// empty (e.g. the adjacency of a graph without edges)
+func idxPtr(s []int32) *C.idx_t {
+	if len(s) == 0 {
+		return nil
+	}
+	return (*C.idx_t)(unsafe.Pointer(&s[0]))
+}
+
+// Errors returned for METIS status codes
+var (
+	ErrInput  = errors.New("METIS error: erroneous inputs and/or options")
+	ErrMemory = errors.New("METIS error: insufficient memory")
+	ErrMETIS  = errors.New("METIS error: general error")
+)
+
+// InputError describes the likely cause of a METIS input error. METIS itself
+// only reports that its inputs were rejected, so the cause is diagnosed by
+// re-checking the arguments on the Go side. It wraps ErrInput.
+type InputError struct {
+	Cause string // Likely reason METIS rejected its inputs
+}
+
+func (e *InputError) Error() string {
+	return ErrInput.Error() + ": " + e.Cause
+}
+
+func (e *InputError) Unwrap() error {
+	return ErrInput
+}
+
+// diagnoseGraphInput explains why METIS rejected a graph partitioning call,
+// returning ErrInput itself when no cause can be found
+func diagnoseGraphInput(xadj, adjncy, vwgt, adjwgt []int32, nparts int32, tpwgts, ubvec []float32) error {
+	if err := ValidateGraph(xadj, adjncy); err != nil {
+		return &InputError{Cause: err.Error()}
+	}
+
+	nvtxs := int32(len(xadj) - 1)
+	switch {
+	case nparts < 1:
+		return &InputError{Cause: fmt.Sprintf("nparts(%d) must be at least 1", nparts)}
+	case nparts > nvtxs:
+		return &InputError{Cause: fmt.Sprintf("nparts(%d) exceeds nvtxs(%d)", nparts, nvtxs)}
+	}
+
+	for i, w := range vwgt {
+		if w < 0 {
+			return &InputError{Cause: fmt.Sprintf("vwgt[%d] is negative (%d)", i, w)}
+		}
+	}
+	for i, w := range adjwgt {
+		if w <= 0 {
+			return &InputError{Cause: fmt.Sprintf("adjwgt[%d] must be positive, got %d", i, w)}
+		}
+	}
+
+	if tpwgts != nil {
+		if len(tpwgts) != int(nparts) {
+			return &InputError{Cause: fmt.Sprintf("tpwgts has %d entries, expected nparts(%d)", len(tpwgts), nparts)}
+		}
+		sum := float32(0)
+		for _, w := range tpwgts {
+			sum += w
+		}
+		if sum < 0.99 || sum > 1.01 {
+			return &InputError{Cause: fmt.Sprintf("tpwgts sum to %.3f, expected 1", sum)}
+		}
+	}
+	for i, u := range ubvec {
+		if u <= 1 {
+			return &InputError{Cause: fmt.Sprintf("ubvec[%d] must exceed 1, got %.3f", i, u)}
+		}
+	}
+
+	return ErrInput
+}
+
 // getError converts METIS error codes to Go errors
 func getError(status C.int) error {
 	switch status {
 	case ErrorInput:
-		return errors.New("METIS error: erroneous inputs and/or options")
+		return ErrInput
 	case ErrorMemory:
-		return errors.New("METIS error: insufficient memory")
+		return ErrMemory
 	case Error:
-		return errors.New("METIS error: general error")
+		return ErrMETIS
 	default:
 		return fmt.Errorf("METIS error: unknown error code %d", status)
 	}
@@ -26,16 +26,234 @@ func TestSetDefaultOptions(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestValidateGraph(t *testing.T) {
+	assert.NoError(t, ValidateGraph([]int32{0, 1, 2}, []int32{1, 0}))
+
+	// A graph without edges is valid
+	assert.NoError(t, ValidateGraph([]int32{0, 0, 0}, nil))
+
+	assert.Error(t, ValidateGraph(nil, nil))
+	assert.Error(t, ValidateGraph([]int32{0}, nil))
+	assert.Error(t, ValidateGraph([]int32{1, 2}, []int32{0, 0}))
+	assert.Error(t, ValidateGraph([]int32{0, 2, 1}, []int32{1, 0}))
+	assert.Error(t, ValidateGraph([]int32{0, 1, 2}, []int32{1, 0, 0}))
+	assert.Error(t, ValidateGraph([]int32{0, 1, 2}, []int32{1, 2}))
+	assert.Error(t, ValidateGraph([]int32{0, 1, 2}, []int32{-1, 0}))
+
+	// The partitioning functions reject malformed input instead of crashing
+	_, _, err := PartGraphKway([]int32{0, 1, 2}, []int32{1, 7}, 2, nil)
+	assert.ErrorContains(t, err, "adjncy index 1")
+	_, _, err = PartGraphRecursive(nil, nil, 2, nil)
+	assert.Error(t, err)
+	_, _, err = NodeND([]int32{0, 2, 1}, []int32{1, 0}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestValidateMesh(t *testing.T) {
+	// Two triangles over 4 nodes
+	eptr := []int32{0, 3, 6}
+	eind := []int32{0, 1, 2, 1, 3, 2}
+	assert.NoError(t, ValidateMesh(2, 4, eptr, eind))
+
+	assert.Error(t, ValidateMesh(0, 4, []int32{0}, nil))
+	assert.Error(t, ValidateMesh(2, 0, eptr, eind))
+	assert.Error(t, ValidateMesh(3, 4, eptr, eind))
+	assert.Error(t, ValidateMesh(2, 4, []int32{1, 3, 6}, eind))
+	assert.Error(t, ValidateMesh(2, 4, []int32{0, 4, 3}, eind))
+	assert.Error(t, ValidateMesh(2, 4, eptr, eind[:5]))
+	assert.ErrorContains(t, ValidateMesh(2, 3, eptr, eind), "eind index 4 holds node 3")
+	assert.Error(t, ValidateMesh(2, 4, eptr, []int32{0, 1, 2, 1, -1, 2}))
+
+	// The mesh functions reject malformed input instead of crashing
+	_, _, err := MeshToDual(2, 3, eptr, eind, 2)
+	assert.ErrorContains(t, err, "eind index 4")
+	_, _, err = MeshToNodal(2, 4, eptr[:2], eind)
+	assert.Error(t, err)
+	_, _, _, err = PartMeshDual(2, 4, []int32{0, 4, 3}, eind, nil, nil, 2, 2, nil, nil)
+	assert.Error(t, err)
+	_, _, _, err = PartMeshNodal(2, 3, eptr, eind, nil, nil, 2, nil, nil)
+	assert.Error(t, err)
+	_, _, err = PartMeshDualElemOnly(2, 4, eptr, eind[:5], nil, nil, 2, 2, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestInputError(t *testing.T) {
+	xadj := []int32{0, 1, 2, 3, 4}
+	adjncy := []int32{1, 0, 3, 2}
+
+	_, _, err := PartGraphKway(xadj, adjncy, 0, nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInput)
+
+	var inputErr *InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, "nparts(0) must be at least 1", inputErr.Cause)
+
+	// Diagnosis of the remaining checks
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, nil, nil, 5, nil, nil), "nparts(5) exceeds nvtxs(4)")
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, []int32{1, -1, 1, 1}, nil, 2, nil, nil), "vwgt[1]")
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, nil, []int32{1, 1, 0, 1}, 2, nil, nil), "adjwgt[2]")
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, nil, nil, 2, []float32{0.5}, nil), "tpwgts has 1 entries")
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, nil, nil, 2, []float32{0.5, 0.2}, nil), "tpwgts sum")
+	assert.ErrorContains(t, diagnoseGraphInput(xadj, adjncy, nil, nil, 2, nil, []float32{1}), "ubvec[0]")
+	assert.Equal(t, ErrInput, diagnoseGraphInput(xadj, adjncy, nil, nil, 2, nil, nil))
+}
+
+func TestSingletonPartition(t *testing.T) {
+	// Complete graph on 4 vertices with edge {u, v} weighted u+v+1
+	xadj := []int32{0, 3, 6, 9, 12}
+	adjncy := []int32{1, 2, 3, 0, 2, 3, 0, 1, 3, 0, 1, 2}
+	adjwgt := make([]int32, len(adjncy))
+	total := int32(0)
+	for u := 0; u < 4; u++ {
+		for j := xadj[u]; j < xadj[u+1]; j++ {
+			adjwgt[j] = int32(u) + adjncy[j] + 1
+			if int32(u) < adjncy[j] {
+				total += adjwgt[j]
+			}
+		}
+	}
+
+	for _, recursive := range []bool{false, true} {
+		part := make([]int32, 4)
+		objval, err := partGraph(recursive, xadj, adjncy, nil, adjwgt, 4, nil, nil, nil, part)
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 2, 3}, part)
+		assert.Equal(t, total, objval)
+		assert.NoError(t, VerifyPartition(xadj, adjncy, nil, adjwgt, 4, objval, part))
+	}
+
+	// Minimizing volume, every vertex talks to the 3 others
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+	opts[OptionObjType] = ObjTypeVol
+	_, objval, err := PartGraphKway(xadj, adjncy, 4, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int32(12), objval)
+}
+
+func TestTargetWeightsLength(t *testing.T) {
+	xadj, adjncy := createGridGraph(4, 4)
+
+	for name, tpwgts := range map[string][]float32{
+		"TooShort": {0.5, 0.5},
+		"TooLong":  {0.25, 0.25, 0.25, 0.125, 0.125},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := PartGraphKwayWeighted(xadj, adjncy, nil, nil, 4, tpwgts, nil, nil)
+			assert.EqualError(t, err, fmt.Sprintf("tpwgts must have nparts*ncon = 4 elements, got %d", len(tpwgts)))
+			_, _, err = PartGraphRecursiveWeighted(xadj, adjncy, nil, nil, 4, tpwgts, nil, nil)
+			assert.ErrorContains(t, err, "tpwgts must have nparts*ncon = 4 elements")
+		})
+	}
+
+	_, _, err := PartGraphKwayWeighted(xadj, adjncy, nil, nil, 2, nil, []float32{1.05, 1.05}, nil)
+	assert.EqualError(t, err, "ubvec must have ncon = 1 elements, got 2")
+	_, _, err = PartGraphRecursiveWeighted(xadj, adjncy, nil, nil, 2, nil, []float32{1.05, 1.05, 1.05}, nil)
+	assert.EqualError(t, err, "ubvec must have ncon = 1 elements, got 3")
+
+	// Zero or one tolerance is fine for a single constraint
+	_, _, err = PartGraphKwayWeighted(xadj, adjncy, nil, nil, 2, nil, []float32{}, nil)
+	assert.NoError(t, err)
+	_, _, err = PartGraphRecursiveWeighted(xadj, adjncy, nil, nil, 2, nil, []float32{1.05}, nil)
+	assert.NoError(t, err)
+
+	_, _, err = PartGraphKwayWeighted(xadj, adjncy, nil, nil, 2, []float32{0.5, 0.5}, []float32{1.05}, nil)
+	assert.NoError(t, err)
+
+	// The mesh partitioners check tpwgts too
+	eptr := []int32{0, 3, 6}
+	eind := []int32{0, 1, 2, 1, 2, 3}
+	_, _, _, err = PartMeshNodal(2, 4, eptr, eind, nil, nil, 2, []float32{1}, nil)
+	assert.Error(t, err)
+	_, _, _, err = PartMeshDual(2, 4, eptr, eind, nil, nil, 2, 2, []float32{1}, nil)
+	assert.Error(t, err)
+	_, _, err = PartMeshDualElemOnly(2, 4, eptr, eind, nil, nil, 2, 2, []float32{1}, nil)
+	assert.Error(t, err)
+}
+
+func TestEdgeWeightSymmetry(t *testing.T) {
+	// Path 0-1-2 whose edge 1->2 disagrees with 2->1
+	xadj := []int32{0, 1, 3, 4}
+	adjncy := []int32{1, 0, 2, 1}
+	adjwgt := []int32{2, 2, 5, 3}
+
+	const msg = "asymmetric edge weight: 1->2 has weight 5 but 2->1 has weight 3"
+	assert.EqualError(t, ValidateEdgeWeights(xadj, adjncy, adjwgt), msg)
+	assert.Error(t, ValidateEdgeWeights(xadj, adjncy, adjwgt[:3]))
+
+	// The check is opt-in per call
+	g := &Graph{Xadj: xadj, Adjncy: adjncy, Adjwgt: adjwgt}
+	_, err := Partition(g, 2)
+	assert.NoError(t, err)
+	_, err = Partition(g, 2, WithEdgeWeightCheck())
+	assert.EqualError(t, err, msg)
+	_, err = (&Partitioner{CheckEdgeWeights: true}).Partition(g, 2)
+	assert.EqualError(t, err, msg)
+
+	for policy, want := range map[WeightPolicy][]int32{
+		WeightPolicyMax:  {2, 2, 5, 5},
+		WeightPolicyMin:  {2, 2, 3, 3},
+		WeightPolicyMean: {2, 2, 4, 4},
+		WeightPolicySum:  {4, 4, 8, 8},
+	} {
+		sym, err := SymmetrizeEdgeWeights(xadj, adjncy, adjwgt, policy)
+		require.NoError(t, err)
+		assert.Equal(t, want, sym, "policy %d", policy)
+		assert.NoError(t, checkEdgeWeightSymmetry(xadj, adjncy, sym))
+	}
+	assert.Equal(t, []int32{2, 2, 5, 3}, adjwgt)
+
+	sym, _ := SymmetrizeEdgeWeights(xadj, adjncy, adjwgt, WeightPolicyMax)
+	_, _, err = PartGraphKwayWeighted(xadj, adjncy, nil, sym, 2, nil, nil, nil)
+	assert.NoError(t, err)
+
+	_, err = SymmetrizeEdgeWeights(xadj, adjncy, adjwgt, WeightPolicy(9))
+	assert.Error(t, err)
+	_, err = SymmetrizeEdgeWeights(xadj, adjncy, adjwgt[:2], WeightPolicyMax)
+	assert.Error(t, err)
+
+	// A missing reverse edge is reported as such
+	assert.EqualError(t, checkEdgeWeightSymmetry([]int32{0, 1, 1}, []int32{1}, []int32{1}),
+		"edge 0->1 has no reverse edge 1->0")
+}
+
+func TestImbalance(t *testing.T) {
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	// Defaults depend on the partitioning method
+	assert.Equal(t, DefaultImbalanceKway, GetImbalance(opts))
+	opts[OptionPType] = PTypeRB
+	assert.Equal(t, DefaultImbalanceRecursive, GetImbalance(opts))
+	assert.Equal(t, DefaultImbalanceKway, GetImbalance(nil))
+
+	require.NoError(t, SetImbalance(opts, 1.03))
+	assert.Equal(t, int32(30), opts[OptionUFactor])
+	assert.InDelta(t, 1.03, GetImbalance(opts), 1e-9)
+
+	require.NoError(t, SetImbalance(opts, 1.001))
+	assert.Equal(t, int32(1), opts[OptionUFactor])
+
+	require.NoError(t, SetImbalance(opts, 1.5))
+	assert.Equal(t, int32(500), opts[OptionUFactor])
+	assert.InDelta(t, 1.5, GetImbalance(opts), 1e-9)
+
+	assert.Error(t, SetImbalance(opts, 0.9))
+	assert.Error(t, SetImbalance(make([]int32, 10), 1.03))
+}
+
 // Test_PartGraph emulates the C test function Test_PartGraph
 func TestPartGraph(t *testing.T) {
 	// Create a test graph similar to C tests - need larger graph for many partitions
 	nvtxs := 1000 // Increased size for better partitioning
 	xadj, adjncy := createRandomGraph(nvtxs)
+	rng := rand.New(rand.NewSource(7))
 
 	// Create vertex weights
 	vwgt := make([]int32, nvtxs)
 	for i := 0; i < nvtxs; i++ {
-		vwgt[i] = int32(1 + rand.Intn(10))
+		vwgt[i] = int32(1 + rng.Intn(10))
 	}
 
 	// Create edge weights
@@ -44,7 +262,7 @@ func TestPartGraph(t *testing.T) {
 		for j := xadj[i]; j < xadj[i+1]; j++ {
 			k := adjncy[j]
 			if i < int(k) {
-				adjwgt[j] = int32(1 + rand.Intn(5))
+				adjwgt[j] = int32(1 + rng.Intn(5))
 				// Find reverse edge and set same weight
 				for jj := xadj[k]; jj < xadj[k+1]; jj++ {
 					if adjncy[jj] == int32(i) {
@@ -228,11 +446,12 @@ func TestND(t *testing.T) {
 	// Create a test graph
 	nvtxs := 100
 	xadj, adjncy := createRandomGraph(nvtxs)
+	rng := rand.New(rand.NewSource(7))
 
 	// Create vertex weights
 	vwgt := make([]int32, nvtxs)
 	for i := 0; i < nvtxs; i++ {
-		vwgt[i] = int32(1 + rand.Intn(10))
+		vwgt[i] = int32(1 + rng.Intn(10))
 	}
 
 	opts := make([]int32, NoOptions)
@@ -278,6 +497,88 @@ func TestND(t *testing.T) {
 	})
 }
 
+func TestOrderingOptions(t *testing.T) {
+	// Replace every vertex of a grid with a clique of 3 twins that share all
+	// their neighbors, so twins have identical adjacency lists. Twin c of grid
+	// vertex v is vertex c*n+v, so twins are not numbered consecutively.
+	const twins = 3
+	gxadj, gadjncy := createGridGraph(8, 8)
+	n := len(gxadj) - 1
+	twin := func(v, c int) int { return c*n + v }
+	xadj := make([]int32, n*twins+1)
+	adjncy := []int32{}
+	for u := 0; u < n*twins; u++ {
+		v, c := u%n, u/n
+		for d := 0; d < twins; d++ {
+			if d != c {
+				adjncy = append(adjncy, int32(twin(v, d)))
+			}
+		}
+		for j := gxadj[v]; j < gxadj[v+1]; j++ {
+			for d := 0; d < twins; d++ {
+				adjncy = append(adjncy, int32(twin(int(gadjncy[j]), d)))
+			}
+		}
+		xadj[u+1] = int32(len(adjncy))
+	}
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	require.NoError(t, SetCompress(opts, true))
+	assert.Equal(t, int32(1), opts[OptionCompress])
+	perm, iperm, err := NodeND(xadj, adjncy, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, 0, verifyND(n*twins, perm, iperm))
+
+	// Compression numbers each group of twins consecutively
+	for v := 0; v < n; v++ {
+		lo, hi := iperm[twin(v, 0)], iperm[twin(v, 0)]
+		for c := 1; c < twins; c++ {
+			p := iperm[twin(v, c)]
+			if p < lo {
+				lo = p
+			}
+			if p > hi {
+				hi = p
+			}
+		}
+		assert.Equal(t, int32(twins-1), hi-lo, "twins of vertex %d are not contiguous", v)
+	}
+
+	require.NoError(t, SetCompress(opts, false))
+	assert.Equal(t, int32(0), opts[OptionCompress])
+	uperm, uiperm, err := NodeND(xadj, adjncy, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, 0, verifyND(n*twins, uperm, uiperm))
+	assert.NotEqual(t, perm, uperm, "compression did not change the ordering")
+
+	require.NoError(t, SetCCOrder(opts, true))
+	assert.Equal(t, int32(1), opts[OptionCCOrder])
+	perm, iperm, err = NodeND(xadj, adjncy, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 0, verifyND(n*twins, perm, iperm))
+
+	assert.Error(t, SetCompress(make([]int32, 3), true))
+	assert.Error(t, SetCCOrder(nil, true))
+}
+
+func TestNodeNDBase(t *testing.T) {
+	xadj, adjncy := createGridGraph(4, 4)
+
+	perm, iperm, err := NodeND(xadj, adjncy, nil, nil)
+	require.NoError(t, err)
+	perm1, iperm1, err := NodeNDBase(xadj, adjncy, nil, nil, 1)
+	require.NoError(t, err)
+	for i := range perm {
+		assert.Equal(t, perm[i]+1, perm1[i])
+		assert.Equal(t, iperm[i]+1, iperm1[i])
+	}
+
+	_, _, err = NodeNDBase([]int32{0, 2, 1}, []int32{1, 0}, nil, nil, 1)
+	assert.Error(t, err)
+}
+
 func TestMeshPartitioning(t *testing.T) {
 	// Create a simple mesh with multiple tetrahedra
 	ne := int32(10) // Number of elements
@@ -349,6 +650,49 @@ func TestMeshPartitioning(t *testing.T) {
 		assert.Len(t, npart, int(nn))
 		assert.GreaterOrEqual(t, objval, int32(0))
 	})
+
+	t.Run("PartMeshDualElemOnly", func(t *testing.T) {
+		SetDefaultOptions(opts)
+		opts[OptionSeed] = 7
+		nparts := int32(3)
+		ncommon := int32(2)
+
+		objval, epart, err := PartMeshDualElemOnly(ne, nn, eptr, eind, nil, nil, ncommon, nparts, nil, opts)
+		require.NoError(t, err)
+		assert.Len(t, epart, int(ne))
+
+		// Same seed gives the same element partition as the full call
+		fullObjval, fullEpart, _, err := PartMeshDual(ne, nn, eptr, eind, nil, nil, ncommon, nparts, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, fullObjval, objval)
+		assert.Equal(t, fullEpart, epart)
+	})
+
+	t.Run("PartMesh", func(t *testing.T) {
+		SetDefaultOptions(opts)
+		opts[OptionSeed] = 7
+		nparts := int32(3)
+
+		// Each graph type dispatches to its specific function
+		objval, epart, npart, err := PartMesh(ne, nn, eptr, eind, GTypeDual, 2, nparts, nil, nil, nil, opts)
+		require.NoError(t, err)
+		wantObjval, wantEpart, wantNpart, err := PartMeshDual(ne, nn, eptr, eind, nil, nil, 2, nparts, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, wantObjval, objval)
+		assert.Equal(t, wantEpart, epart)
+		assert.Equal(t, wantNpart, npart)
+
+		objval, epart, npart, err = PartMesh(ne, nn, eptr, eind, GTypeNodal, 0, nparts, nil, nil, nil, opts)
+		require.NoError(t, err)
+		wantObjval, wantEpart, wantNpart, err = PartMeshNodal(ne, nn, eptr, eind, nil, nil, nparts, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, wantObjval, objval)
+		assert.Equal(t, wantEpart, epart)
+		assert.Equal(t, wantNpart, npart)
+
+		_, _, _, err = PartMesh(ne, nn, eptr, eind, GType(7), 2, nparts, nil, nil, nil, opts)
+		assert.ErrorContains(t, err, "invalid graph type 7")
+	})
 }
 
 func TestComputeVertexSeparator(t *testing.T) {
@@ -528,7 +872,7 @@ func verifyND(nvtxs int, perm, iperm []int32) int {
 
 // Helper function to create random graphs (already in original test)
 func createRandomGraph(nvtxs int) ([]int32, []int32) {
-	rand.Seed(42)
+	rng := rand.New(rand.NewSource(42))
 	edges := make(map[[2]int]bool)
 
 	// Create a connected graph with random edges
@@ -539,10 +883,10 @@ func createRandomGraph(nvtxs int) ([]int32, []int32) {
 	}
 
 	// Add random edges
-	numExtraEdges := nvtxs + rand.Intn(nvtxs*2)
+	numExtraEdges := nvtxs + rng.Intn(nvtxs*2)
 	for i := 0; i < numExtraEdges; i++ {
-		u := rand.Intn(nvtxs)
-		v := rand.Intn(nvtxs)
+		u := rng.Intn(nvtxs)
+		v := rng.Intn(nvtxs)
 		if u != v {
 			edges[[2]int{u, v}] = true
 			edges[[2]int{v, u}] = true
@@ -0,0 +1,27 @@
+// Package metistest provides assertions for testing code that uses the metis
+// package. It is kept separate so that the metis package itself does not
+// depend on the testing package.
+package metistest
+
+import (
+	"testing"
+
+	"github.com/notargets/go-metis"
+)
+
+// AssertCutMatches recomputes the edge cut of part on g and fails the test if
+// it differs from reportedCut, such as the objval returned by a partitioning
+// call with the default cut objective. Edge weights are honored when g has
+// them. It reports whether the cut matched.
+func AssertCutMatches(t testing.TB, g *metis.Graph, part []int32, reportedCut int32) bool {
+	t.Helper()
+	if len(part) != g.NumVertices() {
+		t.Errorf("partition has %d entries but the graph has %d vertices", len(part), g.NumVertices())
+		return false
+	}
+	if cut := metis.CalculateEdgeCut(g, part); cut != reportedCut {
+		t.Errorf("reported edge cut %d does not match the recomputed cut %d", reportedCut, cut)
+		return false
+	}
+	return true
+}
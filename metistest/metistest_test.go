@@ -0,0 +1,38 @@
+package metistest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/notargets/go-metis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB captures failures instead of failing the enclosing test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertCutMatches(t *testing.T) {
+	g := metis.GenerateGrid2D(6, 6, false)
+	part, objval, err := metis.PartitionGraph(g, 3, nil)
+	require.NoError(t, err)
+	assert.True(t, AssertCutMatches(t, g, part, objval))
+
+	rec := &recordingTB{TB: t}
+	assert.False(t, AssertCutMatches(rec, g, part, objval+1))
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], fmt.Sprintf("reported edge cut %d", objval+1))
+
+	rec = &recordingTB{TB: t}
+	assert.False(t, AssertCutMatches(rec, g, part[:5], objval))
+	assert.Len(t, rec.errors, 1)
+}
@@ -0,0 +1,144 @@
+package metis
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MutableGraph is an undirected graph that supports cheap incremental edits,
+// for graphs that evolve between partitionings. Adjacency is kept in one map
+// per vertex, so AddEdge, RemoveEdge and SetVertexWeight run in O(1)
+// amortized time, and Freeze produces the CSR Graph the partitioning
+// functions take. Weights default to 1. A MutableGraph is not safe for
+// concurrent use.
+type MutableGraph struct {
+	adj    []map[int32]int32 // Neighbors of each vertex, with edge weights
+	vwgt   []int32
+	nedges int
+}
+
+// NewMutableGraph returns a graph of nvtxs vertices without edges
+func NewMutableGraph(nvtxs int) *MutableGraph {
+	m := &MutableGraph{}
+	for i := 0; i < nvtxs; i++ {
+		m.AddVertex()
+	}
+	return m
+}
+
+// NumVertices returns the number of vertices in the graph
+func (m *MutableGraph) NumVertices() int {
+	return len(m.adj)
+}
+
+// NumEdges returns the number of edges in the graph
+func (m *MutableGraph) NumEdges() int {
+	return m.nedges
+}
+
+// AddVertex appends an isolated vertex of weight 1 and returns its id
+func (m *MutableGraph) AddVertex() int32 {
+	m.adj = append(m.adj, make(map[int32]int32))
+	m.vwgt = append(m.vwgt, 1)
+	return int32(len(m.adj) - 1)
+}
+
+// checkVertex returns an error if v is not a vertex of m
+func (m *MutableGraph) checkVertex(v int32) error {
+	if v < 0 || int(v) >= len(m.adj) {
+		return fmt.Errorf("vertex %d out of range [0, %d)", v, len(m.adj))
+	}
+	return nil
+}
+
+// AddEdge adds the edge {u, v} with the given weight, or sets its weight if
+// the edge already exists. METIS rejects self-loops and non-positive weights,
+// so both are errors here.
+func (m *MutableGraph) AddEdge(u, v, weight int32) error {
+	if err := m.checkVertex(u); err != nil {
+		return err
+	}
+	if err := m.checkVertex(v); err != nil {
+		return err
+	}
+	if u == v {
+		return fmt.Errorf("self-loop on vertex %d", u)
+	}
+	if weight < 1 {
+		return fmt.Errorf("edge {%d, %d} has non-positive weight %d", u, v, weight)
+	}
+
+	if _, ok := m.adj[u][v]; !ok {
+		m.nedges++
+	}
+	m.adj[u][v] = weight
+	m.adj[v][u] = weight
+	return nil
+}
+
+// RemoveEdge removes the edge {u, v}, reporting whether it was present
+func (m *MutableGraph) RemoveEdge(u, v int32) bool {
+	if m.checkVertex(u) != nil || m.checkVertex(v) != nil {
+		return false
+	}
+	if _, ok := m.adj[u][v]; !ok {
+		return false
+	}
+	delete(m.adj[u], v)
+	delete(m.adj[v], u)
+	m.nedges--
+	return true
+}
+
+// SetVertexWeight sets the weight of vertex v
+func (m *MutableGraph) SetVertexWeight(v, weight int32) error {
+	if err := m.checkVertex(v); err != nil {
+		return err
+	}
+	if weight < 0 {
+		return fmt.Errorf("vertex %d has negative weight %d", v, weight)
+	}
+	m.vwgt[v] = weight
+	return nil
+}
+
+// Freeze returns a snapshot of m as a CSR Graph with sorted adjacency lists,
+// sharing no memory with m, so m may keep changing afterwards. Vwgt and
+// Adjwgt are left nil when all vertex or edge weights are 1. A snapshot costs
+// O(n + m log d) for n vertices, m edges and maximum degree d, the price of a
+// full CSR rebuild, so batch edits and freeze only when a partition is needed.
+func (m *MutableGraph) Freeze() *Graph {
+	nvtxs := len(m.adj)
+	g := &Graph{
+		Xadj:   make([]int32, nvtxs+1),
+		Adjncy: make([]int32, 0, 2*m.nedges),
+	}
+	adjwgt := make([]int32, 0, 2*m.nedges)
+	weighted := false
+
+	for u, nbrs := range m.adj {
+		start := len(g.Adjncy)
+		for v := range nbrs {
+			g.Adjncy = append(g.Adjncy, v)
+		}
+		list := g.Adjncy[start:]
+		sort.Slice(list, func(a, b int) bool { return list[a] < list[b] })
+		for _, v := range list {
+			w := nbrs[v]
+			adjwgt = append(adjwgt, w)
+			weighted = weighted || w != 1
+		}
+		g.Xadj[u+1] = int32(len(g.Adjncy))
+	}
+	if weighted {
+		g.Adjwgt = adjwgt
+	}
+
+	for _, w := range m.vwgt {
+		if w != 1 {
+			g.Vwgt = append([]int32(nil), m.vwgt...)
+			break
+		}
+	}
+	return g
+}
@@ -0,0 +1,51 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutableGraph(t *testing.T) {
+	m := NewMutableGraph(4)
+	require.NoError(t, m.AddEdge(0, 1, 1))
+	require.NoError(t, m.AddEdge(2, 1, 1))
+	require.NoError(t, m.AddEdge(2, 3, 1))
+	require.NoError(t, m.AddEdge(3, 0, 1))
+	assert.Equal(t, 4, m.NumEdges())
+
+	g := m.Freeze()
+	assert.Equal(t, []int32{0, 2, 4, 6, 8}, g.Xadj)
+	assert.Equal(t, []int32{1, 3, 0, 2, 1, 3, 0, 2}, g.Adjncy)
+	assert.Nil(t, g.Vwgt)
+	assert.Nil(t, g.Adjwgt)
+
+	// Re-adding an edge updates its weight instead of duplicating it
+	require.NoError(t, m.AddEdge(1, 0, 5))
+	assert.Equal(t, 4, m.NumEdges())
+	assert.True(t, m.RemoveEdge(3, 2))
+	assert.False(t, m.RemoveEdge(3, 2))
+	assert.False(t, m.RemoveEdge(3, 9))
+	require.NoError(t, m.SetVertexWeight(2, 3))
+	v := m.AddVertex()
+	assert.Equal(t, int32(4), v)
+	require.NoError(t, m.AddEdge(v, 2, 1))
+
+	// The earlier snapshot is unaffected
+	assert.Equal(t, []int32{1, 3, 0, 2, 1, 3, 0, 2}, g.Adjncy)
+
+	g = m.Freeze()
+	assert.Equal(t, []int32{0, 2, 4, 6, 7, 8}, g.Xadj)
+	assert.Equal(t, []int32{1, 3, 0, 2, 1, 4, 0, 2}, g.Adjncy)
+	assert.Equal(t, []int32{5, 1, 5, 1, 1, 1, 1, 1}, g.Adjwgt)
+	assert.Equal(t, []int32{1, 1, 3, 1, 1}, g.Vwgt)
+	assert.Equal(t, 4, m.NumEdges())
+	assert.NoError(t, ValidateGraph(g.Xadj, g.Adjncy))
+
+	assert.Error(t, m.AddEdge(1, 1, 1))
+	assert.Error(t, m.AddEdge(0, 5, 1))
+	assert.Error(t, m.AddEdge(0, 2, 0))
+	assert.Error(t, m.SetVertexWeight(-1, 1))
+	assert.Error(t, m.SetVertexWeight(0, -1))
+}
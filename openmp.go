@@ -0,0 +1,60 @@
+package metis
+
+/*
+#cgo linux LDFLAGS: -ldl
+#define _GNU_SOURCE
+#include <dlfcn.h>
+
+// ompLinked reports whether an OpenMP runtime is loaded in the process, which
+// is the case when METIS was built with OpenMP
+static int ompLinked(void) {
+	return dlsym(RTLD_DEFAULT, "omp_get_max_threads") != NULL;
+}
+
+// ompMaxThreads returns omp_get_max_threads(), or 1 without a runtime
+static int ompMaxThreads(void) {
+	int (*fn)(void) = (int (*)(void))dlsym(RTLD_DEFAULT, "omp_get_max_threads");
+	return fn ? fn() : 1;
+}
+
+// ompSetNumThreads calls omp_set_num_threads(n), with n < 1 meaning one
+// thread per processor; it does nothing without a runtime
+static void ompSetNumThreads(int n) {
+	void (*set)(int) = (void (*)(int))dlsym(RTLD_DEFAULT, "omp_set_num_threads");
+	int (*procs)(void) = (int (*)(void))dlsym(RTLD_DEFAULT, "omp_get_num_procs");
+	if (set == NULL) {
+		return;
+	}
+	if (n < 1) {
+		n = procs ? procs() : 1;
+	}
+	set(n);
+}
+*/
+import "C"
+
+// IsOpenMPEnabled reports whether the linked METIS library is multithreaded.
+// METIS 5.2 can be built with OpenMP; such a build pulls an OpenMP runtime into
+// the process, and this looks for that runtime's symbols.
+func IsOpenMPEnabled() bool {
+	return C.ompLinked() != 0
+}
+
+// OpenMPMaxThreads returns the number of threads an OpenMP-enabled METIS will
+// use, or 1 if METIS is not built with OpenMP
+func OpenMPMaxThreads() int {
+	return int(C.ompMaxThreads())
+}
+
+// SetThreadCount makes an OpenMP-enabled METIS use n threads through
+// omp_set_num_threads; n < 1 selects one thread per processor. OpenMP keeps
+// the thread count per OS thread, and Go moves goroutines between OS threads,
+// so the setting reliably reaches METIS only from a goroutine that called
+// runtime.LockOSThread before SetThreadCount and the METIS calls. To set the
+// count for every thread, set OMP_NUM_THREADS in the environment before the
+// process starts instead; changing it from Go is too late, as the OpenMP
+// runtime has already read it. Without an OpenMP build of METIS this is a
+// no-op.
+func SetThreadCount(n int) {
+	C.ompSetNumThreads(C.int(n))
+}
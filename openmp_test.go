@@ -0,0 +1,26 @@
+package metis
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenMP(t *testing.T) {
+	// The OpenMP thread count is per OS thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	SetThreadCount(2)
+	if !IsOpenMPEnabled() {
+		assert.Equal(t, 1, OpenMPMaxThreads())
+		return
+	}
+	defer SetThreadCount(0)
+	assert.Equal(t, 2, OpenMPMaxThreads())
+	SetThreadCount(3)
+	assert.Equal(t, 3, OpenMPMaxThreads())
+	SetThreadCount(0)
+	assert.Equal(t, runtime.NumCPU(), OpenMPMaxThreads())
+}
@@ -0,0 +1,316 @@
+package metis
+
+import "fmt"
+
+// Option configures a call to Partition
+type Option func(*partitionSettings) error
+
+// partitionSettings collects the effect of the Options given to Partition
+type partitionSettings struct {
+	options []int32   // METIS options array, starting from the defaults
+	tpwgts  []float32 // Target partition weights, nil for equal parts
+
+	mustBeConnected  bool // Reject disconnected graphs before calling METIS
+	checkEdgeWeights bool // Reject asymmetric edge weights before calling METIS
+}
+
+func newPartitionSettings(opts []Option) (*partitionSettings, error) {
+	s := &partitionSettings{options: make([]int32, NoOptions)}
+	if err := SetDefaultOptions(s.options); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// WithSeed sets the seed of the METIS random number generator, making
+// partitions reproducible
+func WithSeed(seed int32) Option {
+	return func(s *partitionSettings) error {
+		s.options[OptionSeed] = seed
+		return nil
+	}
+}
+
+// WithObjective selects the objective to minimize, ObjectiveCut or
+// ObjectiveVol
+func WithObjective(objective ObjType) Option {
+	return func(s *partitionSettings) error {
+		if objective != ObjectiveCut && objective != ObjectiveVol {
+			return fmt.Errorf("invalid objective %s, want ObjectiveCut or ObjectiveVol", objective)
+		}
+		s.options[OptionObjType] = int32(objective)
+		return nil
+	}
+}
+
+// WithMethod selects recursive bisection (MethodRB) or k-way partitioning
+// (MethodKway, the default)
+func WithMethod(method PType) Option {
+	return func(s *partitionSettings) error {
+		if method != MethodRB && method != MethodKway {
+			return fmt.Errorf("invalid method %s, want MethodRB or MethodKway", method)
+		}
+		s.options[OptionPType] = int32(method)
+		return nil
+	}
+}
+
+// WithImbalance sets the maximum allowed ratio of the heaviest partition to
+// the average, as SetImbalance does
+func WithImbalance(maxImbalance float64) Option {
+	return func(s *partitionSettings) error {
+		return SetImbalance(s.options, maxImbalance)
+	}
+}
+
+// WithTargetWeights sets the fraction of the total vertex weight each
+// partition should receive. There must be one positive entry per partition,
+// summing to 1; see CapacityTargetWeights for deriving them from capacities.
+func WithTargetWeights(tpwgts []float32) Option {
+	return func(s *partitionSettings) error {
+		s.tpwgts = append([]float32(nil), tpwgts...)
+		return nil
+	}
+}
+
+// WithMustBeConnected makes Partition fail with an error wrapping
+// ErrDisconnected, before calling METIS, if the graph is disconnected. The
+// check is an extra breadth-first search over the graph and is off by
+// default; enable it when a disconnected input would be a bug.
+func WithMustBeConnected() Option {
+	return func(s *partitionSettings) error {
+		s.mustBeConnected = true
+		return nil
+	}
+}
+
+// WithEdgeWeightCheck makes Partition run ValidateEdgeWeights on a weighted
+// graph before calling METIS, failing on the first edge whose weight differs
+// between its two directions. The check is off by default.
+func WithEdgeWeightCheck() Option {
+	return func(s *partitionSettings) error {
+		s.checkEdgeWeights = true
+		return nil
+	}
+}
+
+// Effective values METIS 5 uses for options left at -1
+const (
+	defaultSeed     = 4321 // GKlib seeds its generator with 4321 when seed is -1
+	defaultNIter    = 10
+	defaultNCuts    = 1
+	defaultMinConn  = 0
+	defaultContig   = 0
+	defaultNo2Hop   = 0
+	defaultUFactorK = 30
+	defaultUFactorR = 1
+
+	// Defaults of METIS_NodeND
+	defaultUFactorND = 200
+	defaultNSeps     = 1
+	defaultCompress  = 1
+	defaultCCOrder   = 0
+	defaultPFactor   = 0
+)
+
+// ResolvedConfig is the human-readable interpretation of a METIS options
+// array, with every -1 entry replaced by the value METIS actually uses
+type ResolvedConfig struct {
+	Method              string   // "rb" or "kway"
+	Objective           string   // "cut" or "vol"
+	Coarsening          string   // "rm" or "shem"
+	InitialPartitioning string   // "grow", "random", "edge", "node" or "metisrb"
+	Refinement          string   // "fm", "greedy", "sep2sided" or "sep1sided"
+	NIter               int32    // Refinement iterations per level
+	NCuts               int32    // Partitionings computed, keeping the best
+	Seed                int32    // Effective random seed
+	UFactor             int32    // Allowed imbalance in thousandths
+	Imbalance           float64  // Maximum partition weight over the average
+	MinConn             bool     // Minimize the connectivity of the quotient graph
+	Contig              bool     // Force contiguous partitions
+	No2Hop              bool     // Skip 2-hop matching during coarsening
+	Defaulted           []string // Options left at -1, in array order
+}
+
+// ResolveOptions interprets an options array for graph partitioning, as a
+// dry run showing what METIS will do with it. Entries at -1 are resolved to
+// the METIS 5 defaults for the selected method, which differ between
+// recursive bisection and k-way for the initial partitioning, refinement and
+// imbalance. A nil or wrongly sized array resolves as all defaults, matching
+// how the partitioning functions treat it. Values METIS does not know are
+// shown as "unknown(n)".
+func ResolveOptions(options []int32) ResolvedConfig {
+	if len(options) != NoOptions {
+		options = nil
+	}
+
+	var defaulted []string
+	get := func(index int, name string, def int32) int32 {
+		if options == nil || options[index] == -1 {
+			defaulted = append(defaulted, name)
+			return def
+		}
+		return options[index]
+	}
+
+	r := ResolvedConfig{}
+	ptype := PType(get(OptionPType, "ptype", int32(MethodKway)))
+	recursive := ptype == MethodRB
+	r.Method = ptype.String()
+	r.Objective = ObjType(get(OptionObjType, "objtype", int32(ObjectiveCut))).String()
+	r.Coarsening = CType(get(OptionCType, "ctype", int32(CoarsenSHEM))).String()
+
+	ipDefault, rDefault, uDefault := InitialMetisRB, RefineGreedy, int32(defaultUFactorK)
+	if recursive {
+		ipDefault, rDefault, uDefault = InitialGrow, RefineFM, defaultUFactorR
+	}
+	r.InitialPartitioning = IPType(get(OptionIPType, "iptype", int32(ipDefault))).String()
+	r.Refinement = RType(get(OptionRType, "rtype", int32(rDefault))).String()
+
+	r.NIter = get(OptionNIter, "niter", defaultNIter)
+	r.NCuts = get(OptionNCuts, "ncuts", defaultNCuts)
+	r.Seed = get(OptionSeed, "seed", defaultSeed)
+	r.UFactor = get(OptionUFactor, "ufactor", uDefault)
+	r.Imbalance = 1 + float64(r.UFactor)/1000
+	r.MinConn = get(OptionMinConn, "minconn", defaultMinConn) == 1
+	r.Contig = get(OptionContig, "contig", defaultContig) == 1
+	r.No2Hop = get(OptionNo2Hop, "no2hop", defaultNo2Hop) == 1
+	r.Defaulted = defaulted
+
+	return r
+}
+
+// DefaultOptionValues returns, keyed by the option names used by
+// ResolveOptions, the values METIS 5.1 substitutes for options left at -1 by
+// SetDefaultOptions. Where the default depends on the routine it is listed per
+// routine, as in "metisrb (kway), grow (rb), edge (NodeND)". The map is for
+// reference only; ResolveOptions applies the same defaults to an actual
+// options array. A new map is returned on every call.
+func DefaultOptionValues() map[string]string {
+	perRoutine := func(kway, rb, nd interface{}) string {
+		return fmt.Sprintf("%v (kway), %v (rb), %v (NodeND)", kway, rb, nd)
+	}
+	return map[string]string{
+		"ptype":     MethodKway.String() + " (PartMeshDual, PartMeshNodal)",
+		"objtype":   ObjectiveCut.String(),
+		"ctype":     CoarsenSHEM.String(),
+		"iptype":    perRoutine(InitialMetisRB, InitialGrow, InitialEdge),
+		"rtype":     perRoutine(RefineGreedy, RefineFM, RefineSep1Sided),
+		"niter":     fmt.Sprint(defaultNIter),
+		"ncuts":     fmt.Sprint(defaultNCuts),
+		"nseps":     fmt.Sprint(defaultNSeps),
+		"seed":      fmt.Sprintf("%d (fixed, so runs are reproducible)", defaultSeed),
+		"ufactor":   perRoutine(defaultUFactorK, defaultUFactorR, defaultUFactorND),
+		"minconn":   fmt.Sprint(defaultMinConn),
+		"contig":    fmt.Sprint(defaultContig),
+		"no2hop":    fmt.Sprint(defaultNo2Hop),
+		"compress":  fmt.Sprint(defaultCompress),
+		"ccorder":   fmt.Sprint(defaultCCOrder),
+		"pfactor":   fmt.Sprint(defaultPFactor),
+		"numbering": "0 (C-style)",
+		"dbglvl":    "0",
+	}
+}
+
+// String formats the configuration on one line, as a CLI would echo it
+func (r ResolvedConfig) String() string {
+	return fmt.Sprintf("method=%s objective=%s ctype=%s iptype=%s rtype=%s niter=%d ncuts=%d seed=%d imbalance=%.3f minconn=%t contig=%t no2hop=%t",
+		r.Method, r.Objective, r.Coarsening, r.InitialPartitioning, r.Refinement,
+		r.NIter, r.NCuts, r.Seed, r.Imbalance, r.MinConn, r.Contig, r.No2Hop)
+}
+
+// optionName returns the name of an option value, or unknown(n)
+func optionName(value int32, names map[int32]string) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", value)
+}
+
+// Names of the enumerated option values, as the METIS command line tools
+// spell them
+var (
+	ptypeNames   = map[int32]string{PTypeRB: "rb", PTypeKway: "kway"}
+	gtypeNames   = map[int32]string{GTypeDual: "dual", GTypeNodal: "nodal"}
+	ctypeNames   = map[int32]string{CTypeRM: "rm", CTypeSHEM: "shem"}
+	objtypeNames = map[int32]string{ObjTypeCut: "cut", ObjTypeVol: "vol", ObjTypeNode: "node"}
+	iptypeNames  = map[int32]string{
+		IPTypeGrow: "grow", IPTypeRandom: "random", IPTypeEdge: "edge",
+		IPTypeNode: "node", IPTypeMetisRB: "metisrb",
+	}
+	rtypeNames = map[int32]string{
+		RTypeFM: "fm", RTypeGreedy: "greedy",
+		RTypeSep2Sided: "sep2sided", RTypeSep1Sided: "sep1sided",
+	}
+)
+
+// String returns the METIS command line name of the method, such as "kway"
+func (t PType) String() string { return optionName(int32(t), ptypeNames) }
+
+// String returns the METIS command line name of the graph type, such as "dual"
+func (t GType) String() string { return optionName(int32(t), gtypeNames) }
+
+// String returns the METIS command line name of the coarsening scheme, such
+// as "shem"
+func (t CType) String() string { return optionName(int32(t), ctypeNames) }
+
+// String returns the METIS command line name of the initial partitioning
+// algorithm, such as "metisrb"
+func (t IPType) String() string { return optionName(int32(t), iptypeNames) }
+
+// String returns the METIS command line name of the refinement algorithm, such
+// as "greedy"
+func (t RType) String() string { return optionName(int32(t), rtypeNames) }
+
+// String returns the METIS command line name of the objective, such as "cut"
+func (t ObjType) String() string { return optionName(int32(t), objtypeNames) }
+
+// SetPType stores the partitioning method t in opts[OptionPType]. Like the
+// other typed setters below, it only takes a value of its own option's type,
+// so SetRType(opts, CoarsenRM) does not compile where the plain assignment
+// opts[OptionRType] = CTypeRM silently selects whichever refinement algorithm
+// shares the value. Values outside the known constants are rejected.
+func SetPType(opts []int32, t PType) error {
+	return setEnumOption(opts, OptionPType, t, int32(t), ptypeNames)
+}
+
+// SetGType stores the graph type t in opts[OptionGType]
+func SetGType(opts []int32, t GType) error {
+	return setEnumOption(opts, OptionGType, t, int32(t), gtypeNames)
+}
+
+// SetCType stores the coarsening scheme t in opts[OptionCType]
+func SetCType(opts []int32, t CType) error {
+	return setEnumOption(opts, OptionCType, t, int32(t), ctypeNames)
+}
+
+// SetIPType stores the initial partitioning algorithm t in opts[OptionIPType]
+func SetIPType(opts []int32, t IPType) error {
+	return setEnumOption(opts, OptionIPType, t, int32(t), iptypeNames)
+}
+
+// SetRType stores the refinement algorithm t in opts[OptionRType]
+func SetRType(opts []int32, t RType) error {
+	return setEnumOption(opts, OptionRType, t, int32(t), rtypeNames)
+}
+
+// SetObjType stores the objective t in opts[OptionObjType]
+func SetObjType(opts []int32, t ObjType) error {
+	return setEnumOption(opts, OptionObjType, t, int32(t), objtypeNames)
+}
+
+func setEnumOption(opts []int32, index int, value fmt.Stringer, raw int32, names map[int32]string) error {
+	if len(opts) != NoOptions {
+		return fmt.Errorf("options array must have %d elements", NoOptions)
+	}
+	if _, known := names[raw]; !known {
+		return fmt.Errorf("invalid %T value %s", value, value)
+	}
+	opts[index] = raw
+	return nil
+}
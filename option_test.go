@@ -0,0 +1,93 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveOptions(t *testing.T) {
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	r := ResolveOptions(opts)
+	assert.Equal(t, "kway", r.Method)
+	assert.Equal(t, "cut", r.Objective)
+	assert.Equal(t, "shem", r.Coarsening)
+	assert.Equal(t, "metisrb", r.InitialPartitioning)
+	assert.Equal(t, "greedy", r.Refinement)
+	assert.Equal(t, int32(4321), r.Seed)
+	assert.InDelta(t, 1.03, r.Imbalance, 1e-12)
+	assert.Contains(t, r.Defaulted, "seed")
+	assert.Equal(t, r, ResolveOptions(nil))
+
+	// Recursive bisection changes the method-specific defaults
+	opts[OptionPType] = PTypeRB
+	opts[OptionSeed] = 42
+	opts[OptionContig] = 1
+	opts[OptionRType] = 9
+	r = ResolveOptions(opts)
+	assert.Equal(t, "rb", r.Method)
+	assert.Equal(t, "grow", r.InitialPartitioning)
+	assert.Equal(t, "unknown(9)", r.Refinement)
+	assert.Equal(t, int32(42), r.Seed)
+	assert.True(t, r.Contig)
+	assert.InDelta(t, 1.001, r.Imbalance, 1e-12)
+	assert.NotContains(t, r.Defaulted, "seed")
+	assert.NotContains(t, r.Defaulted, "ptype")
+
+	assert.Equal(t, "method=rb objective=cut ctype=shem iptype=grow rtype=unknown(9) niter=10 ncuts=1 seed=42 imbalance=1.001 minconn=false contig=true no2hop=false", r.String())
+}
+
+func TestDefaultOptionValues(t *testing.T) {
+	d := DefaultOptionValues()
+	assert.Equal(t, "shem", d["ctype"])
+	assert.Equal(t, "10", d["niter"])
+	assert.Equal(t, "1", d["ncuts"])
+	assert.Equal(t, "30 (kway), 1 (rb), 200 (NodeND)", d["ufactor"])
+	assert.Equal(t, "greedy (kway), fm (rb), sep1sided (NodeND)", d["rtype"])
+
+	// Every option ResolveOptions can report as defaulted is documented
+	for _, name := range ResolveOptions(nil).Defaulted {
+		assert.Contains(t, d, name)
+	}
+
+	// Callers cannot alter the reference
+	d["niter"] = "99"
+	assert.Equal(t, "10", DefaultOptionValues()["niter"])
+}
+
+func TestOptionEnums(t *testing.T) {
+	assert.Equal(t, "kway", MethodKway.String())
+	assert.Equal(t, "vol", ObjectiveVol.String())
+	assert.Equal(t, "rm", CoarsenRM.String())
+	assert.Equal(t, "edge", InitialEdge.String())
+	assert.Equal(t, "sep1sided", RefineSep1Sided.String())
+	assert.Equal(t, "nodal", GraphNodal.String())
+
+	// The typed values equal the untyped constants stored in options arrays
+	assert.Equal(t, int32(PTypeRB), int32(MethodRB))
+	assert.Equal(t, int32(RTypeSep2Sided), int32(RefineSep2Sided))
+	assert.Equal(t, "unknown(7)", RType(7).String())
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	// Each setter only takes its own type, so SetRType(opts, CoarsenRM) does
+	// not compile
+	assert.NoError(t, SetRType(opts, RefineFM))
+	assert.Equal(t, int32(RTypeFM), opts[OptionRType])
+	assert.NoError(t, SetPType(opts, MethodRB))
+	assert.Equal(t, int32(PTypeRB), opts[OptionPType])
+	assert.NoError(t, SetObjType(opts, ObjectiveVol))
+	assert.NoError(t, SetCType(opts, CoarsenRM))
+	assert.NoError(t, SetIPType(opts, InitialEdge))
+	assert.NoError(t, SetGType(opts, GraphNodal))
+	assert.Equal(t, []int32{int32(ObjTypeVol), int32(CTypeRM), int32(IPTypeEdge), int32(GTypeNodal)},
+		[]int32{opts[OptionObjType], opts[OptionCType], opts[OptionIPType], opts[OptionGType]})
+
+	// Values outside the known constants are rejected
+	assert.ErrorContains(t, SetIPType(opts, IPType(42)), "invalid metis.IPType value unknown(42)")
+	assert.Equal(t, int32(IPTypeEdge), opts[OptionIPType])
+	assert.Error(t, SetRType(opts[:3], RefineFM))
+}
@@ -0,0 +1,43 @@
+package metis
+
+// EliminationTree computes the elimination tree of a symmetric sparse matrix
+// with the nonzero structure of the CSR graph, eliminated in the order given
+// by perm. perm follows the NodeND convention: row/column k of the permuted
+// matrix is row/column perm[k] of the original, so perm[k] is the original
+// vertex eliminated k-th.
+//
+// The result is indexed by elimination position: parent[k] is the position of
+// the parent of the k-th eliminated vertex, or -1 for the root of each tree in
+// the forest. It uses Liu's algorithm with path compression, which runs in
+// nearly linear time in the number of edges.
+func EliminationTree(xadj, adjncy, perm []int32) (parent []int32) {
+	nvtxs := len(xadj) - 1
+	iperm := make([]int32, nvtxs)
+	for k, v := range perm {
+		iperm[v] = int32(k)
+	}
+
+	parent = make([]int32, nvtxs)
+	ancestor := make([]int32, nvtxs)
+	for k := 0; k < nvtxs; k++ {
+		parent[k] = -1
+		ancestor[k] = -1
+
+		v := perm[k]
+		for j := xadj[v]; j < xadj[v+1]; j++ {
+			// Follow each earlier neighbor up to the root of its subtree,
+			// compressing the path to point at k
+			r := iperm[adjncy[j]]
+			for r != -1 && r < int32(k) {
+				next := ancestor[r]
+				ancestor[r] = int32(k)
+				if next == -1 {
+					parent[r] = int32(k)
+				}
+				r = next
+			}
+		}
+	}
+
+	return parent
+}
@@ -0,0 +1,80 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEliminationTree(t *testing.T) {
+	// Path 0-1-2-3 in natural order is a chain
+	xadj := []int32{0, 1, 3, 5, 6}
+	adjncy := []int32{1, 0, 2, 1, 3, 2}
+	assert.Equal(t, []int32{1, 2, 3, -1}, EliminationTree(xadj, adjncy, []int32{0, 1, 2, 3}))
+
+	// Star with center 0: eliminating leaves first makes the center the root
+	xadj = []int32{0, 3, 4, 5, 6}
+	adjncy = []int32{1, 2, 3, 0, 0, 0}
+	assert.Equal(t, []int32{3, 3, 3, -1}, EliminationTree(xadj, adjncy, []int32{1, 2, 3, 0}))
+
+	// Eliminating the center first fills in the leaves into a chain
+	assert.Equal(t, []int32{1, 2, 3, -1}, EliminationTree(xadj, adjncy, []int32{0, 1, 2, 3}))
+
+	// Disconnected vertices are roots of their own trees
+	assert.Equal(t, []int32{-1, -1}, EliminationTree([]int32{0, 0, 0}, nil, []int32{1, 0}))
+
+	t.Run("MatchesSymbolicFactorization", func(t *testing.T) {
+		nvtxs := 60
+		xadj, adjncy := createRandomGraph(nvtxs)
+		opts := make([]int32, NoOptions)
+		SetDefaultOptions(opts)
+		perm, _, err := NodeND(xadj, adjncy, nil, opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, symbolicEtree(xadj, adjncy, perm), EliminationTree(xadj, adjncy, perm))
+	})
+}
+
+// symbolicEtree computes the elimination tree by explicit symbolic
+// elimination: the parent of k is the first later vertex in its filled column
+func symbolicEtree(xadj, adjncy, perm []int32) []int32 {
+	n := len(perm)
+	iperm := make([]int32, n)
+	for k, v := range perm {
+		iperm[v] = int32(k)
+	}
+
+	adj := make([]map[int32]bool, n)
+	for k := range adj {
+		adj[k] = make(map[int32]bool)
+	}
+	for k, v := range perm {
+		for j := xadj[v]; j < xadj[v+1]; j++ {
+			adj[k][iperm[adjncy[j]]] = true
+		}
+	}
+
+	parent := make([]int32, n)
+	for k := 0; k < n; k++ {
+		parent[k] = -1
+		var later []int32
+		for u := range adj[k] {
+			if u > int32(k) {
+				later = append(later, u)
+				if parent[k] == -1 || u < parent[k] {
+					parent[k] = u
+				}
+			}
+		}
+		// Eliminating k connects all its later neighbors
+		for _, a := range later {
+			for _, b := range later {
+				if a != b {
+					adj[a][b] = true
+				}
+			}
+		}
+	}
+	return parent
+}
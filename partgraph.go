@@ -0,0 +1,754 @@
+package metis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// PartitionResult holds the outcome of a graph partitioning
+type PartitionResult struct {
+	Part   []int32 // Partition assignment of each vertex
+	Objval int32   // Edge cut or communication volume, per OptionObjType
+}
+
+// Detach returns a copy of the result that shares no memory with r. Use it to
+// keep a result from a Partitioner with CopyResult unset beyond the next call.
+func (r *PartitionResult) Detach() *PartitionResult {
+	return &PartitionResult{
+		Part:   append([]int32(nil), r.Part...),
+		Objval: r.Objval,
+	}
+}
+
+// Partitioner partitions graphs repeatedly while reusing its partition buffer,
+// which avoids an allocation per call in sweeps over many graphs or nparts
+// values. OptionPType in Options selects recursive bisection or k-way.
+//
+// Aliasing contract: with CopyResult unset, the Part slice of every returned
+// result aliases the partitioner's internal buffer and is overwritten by the
+// next call to Partition. Call Detach on a result to keep it. With CopyResult
+// set, every result owns its Part slice and no buffer is reused. The top-level
+// partitioning functions always return freshly allocated slices.
+//
+// A Partitioner is not safe for concurrent use.
+type Partitioner struct {
+	Options    []int32 // METIS options, nil for defaults
+	CopyResult bool    // Return independent slices instead of the reused buffer
+	// MustBeConnected rejects disconnected graphs with an error wrapping
+	// ErrDisconnected before calling METIS; see WeaklyConnectedCheck
+	MustBeConnected bool
+	// CheckEdgeWeights rejects weighted graphs whose edge weights differ
+	// between the two directions of an edge; see ValidateEdgeWeights
+	CheckEdgeWeights bool
+
+	buf []int32
+}
+
+// Partition partitions g into nparts using its vertex and edge weights
+func (p *Partitioner) Partition(g *Graph, nparts int32) (*PartitionResult, error) {
+	if p.MustBeConnected {
+		if err := g.WeaklyConnectedCheck(); err != nil {
+			return nil, err
+		}
+	}
+	if p.CheckEdgeWeights && g.Adjwgt != nil {
+		if err := ValidateEdgeWeights(g.Xadj, g.Adjncy, g.Adjwgt); err != nil {
+			return nil, err
+		}
+	}
+
+	nvtxs := numVertices(g.Xadj)
+
+	var part []int32
+	if p.CopyResult {
+		part = make([]int32, nvtxs)
+	} else {
+		if cap(p.buf) < nvtxs {
+			p.buf = make([]int32, nvtxs)
+		}
+		part = p.buf[:nvtxs]
+	}
+
+	recursive := len(p.Options) == NoOptions && PType(p.Options[OptionPType]) == MethodRB
+	objval, err := partGraph(recursive, g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, p.Options, part)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartitionResult{Part: part, Objval: objval}, nil
+}
+
+// PartitionGraph partitions g with its vertex and edge weights, using
+// recursive bisection when OptionPType selects it and k-way otherwise
+func PartitionGraph(g *Graph, nparts int32, options []int32) ([]int32, int32, error) {
+	if options != nil && len(options) == NoOptions && PType(options[OptionPType]) == MethodRB {
+		return PartGraphRecursiveWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, options)
+	}
+	return PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, options)
+}
+
+// PartitionDirected partitions a directed graph, given in CSR form with
+// optional edge weights, into nparts. METIS only handles undirected graphs and
+// does not detect one-directional edges, which silently distort the result, so
+// the graph is first made undirected with Symmetrize under policy. The method
+// follows OptionPType as in PartitionGraph. The returned objective value is
+// that of the symmetrized graph, in which an edge given in both directions is
+// counted once with its combined weight.
+func PartitionDirected(xadj, adjncy, adjwgt []int32, nparts int32, policy WeightPolicy, options []int32) ([]int32, int32, error) {
+	g, err := Symmetrize(xadj, adjncy, adjwgt, policy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return PartitionGraph(g, nparts, options)
+}
+
+// PartitionByID partitions a graph whose vertices are identified by arbitrary
+// comparable keys, such as strings or sparse integer ids, and returns the
+// partition of every key. edges lists the neighbors of each key; an edge needs
+// to be listed in one direction only, duplicates count once, and self-loops
+// are ignored. Keys that only appear as neighbors are vertices too. The method
+// follows OptionPType as in PartitionGraph.
+//
+// Keys are numbered in map iteration order, which Go randomizes, so the
+// partition can differ from run to run even with a fixed OptionSeed.
+func PartitionByID[K comparable](edges map[K][]K, nparts int32, options []int32) (map[K]int32, error) {
+	index := make(map[K]int32)
+	var keys []K
+	id := func(k K) int32 {
+		if i, ok := index[k]; ok {
+			return i
+		}
+		i := int32(len(keys))
+		index[k] = i
+		keys = append(keys, k)
+		return i
+	}
+	for k, nbrs := range edges {
+		id(k)
+		for _, n := range nbrs {
+			id(n)
+		}
+	}
+
+	m := NewMutableGraph(len(keys))
+	for k, nbrs := range edges {
+		u := index[k]
+		for _, n := range nbrs {
+			if v := index[n]; v != u {
+				if err := m.AddEdge(u, v, 1); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	part, _, err := PartitionGraph(m.Freeze(), nparts, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[K]int32, len(keys))
+	for i, k := range keys {
+		result[k] = part[i]
+	}
+	return result, nil
+}
+
+// PartGraphKwayRelaxing partitions g with k-way partitioning, starting at
+// startImbalance and loosening the allowed imbalance by step after every
+// ErrInput until the call succeeds or maxImbalance is exceeded. Overly tight
+// balance constraints on hard graphs are a common cause of ErrInput, and this
+// saves tuning the tolerance by hand.
+//
+// The caller's options are not modified. It returns the partition, the edge
+// cut and the imbalance that was finally used. Errors other than ErrInput are
+// returned immediately.
+func PartGraphKwayRelaxing(g *Graph, nparts int32, startImbalance, maxImbalance, step float64, options []int32) ([]int32, int32, float64, error) {
+	if step <= 0 {
+		return nil, 0, 0, fmt.Errorf("step must be positive, got %g", step)
+	}
+
+	opts := make([]int32, NoOptions)
+	if options != nil && len(options) == NoOptions {
+		copy(opts, options)
+	} else if err := SetDefaultOptions(opts); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var lastErr error
+	// The small slack keeps accumulated float error from skipping maxImbalance
+	for imbalance := startImbalance; imbalance <= maxImbalance+1e-9; imbalance += step {
+		if err := SetImbalance(opts, imbalance); err != nil {
+			return nil, 0, 0, err
+		}
+
+		part, cut, err := PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, opts)
+		if err == nil {
+			if imbalance > startImbalance {
+				warnf("PartGraphKwayRelaxing: relaxed imbalance from %g to %g", startImbalance, imbalance)
+			}
+			return part, cut, imbalance, nil
+		}
+		if !errors.Is(err, ErrInput) {
+			return nil, 0, 0, err
+		}
+		debugf("PartGraphKwayRelaxing: imbalance %g rejected: %v", imbalance, err)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return nil, 0, 0, fmt.Errorf("empty imbalance range [%g, %g]", startImbalance, maxImbalance)
+	}
+	return nil, 0, 0, fmt.Errorf("no partition found with imbalance up to %g: %w", maxImbalance, lastErr)
+}
+
+// CapacityTargetWeights converts per-rank capacities (clock speed, memory or
+// any relative measure) into a tpwgts vector: each capacity is divided by the
+// total so the targets sum to 1. Capacities must be non-negative with a
+// positive total. A rank with zero capacity gets a zero target, which METIS
+// rejects, so such ranks should be left out of the partitioning instead.
+func CapacityTargetWeights(capacities []float64) ([]float32, error) {
+	total := 0.0
+	for i, c := range capacities {
+		if c < 0 {
+			return nil, fmt.Errorf("capacity %d is negative (%g)", i, c)
+		}
+		total += c
+	}
+	if total <= 0 {
+		return nil, errors.New("total capacity must be positive")
+	}
+
+	tpwgts := make([]float32, len(capacities))
+	for i, c := range capacities {
+		tpwgts[i] = float32(c / total)
+	}
+	return tpwgts, nil
+}
+
+// PartitionForCluster partitions g for a set of heterogeneous machines, one
+// partition per entry of nodeCapacities, using PartitionGraph's choice of
+// method. part[v] is the index of the machine that receives vertex v, and
+// nparts is len(nodeCapacities).
+//
+// Capacities are relative: each machine's target share of the total vertex
+// weight is its capacity divided by the sum, as in CapacityTargetWeights.
+// Ratios need not be integral; the shares are passed to METIS as fractional
+// tpwgts and met to within the imbalance tolerance, so a small graph cannot
+// track a ratio such as 1:1.5 exactly. Machines with zero capacity receive no
+// vertices; they are left out of the METIS call and the remaining partitions
+// are renumbered back to machine indices.
+func PartitionForCluster(g *Graph, nodeCapacities []float64, options []int32) (part []int32, nparts int32, err error) {
+	tpwgts, err := CapacityTargetWeights(nodeCapacities)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// machine[i] is the index in nodeCapacities of the i-th METIS partition
+	var machine []int32
+	var targets []float32
+	for i, w := range tpwgts {
+		if nodeCapacities[i] > 0 {
+			machine = append(machine, int32(i))
+			targets = append(targets, w)
+		}
+	}
+	nparts = int32(len(nodeCapacities))
+
+	if len(machine) == 1 {
+		part = make([]int32, g.NumVertices())
+		for v := range part {
+			part[v] = machine[0]
+		}
+		return part, nparts, nil
+	}
+
+	k := int32(len(machine))
+	if options != nil && len(options) == NoOptions && PType(options[OptionPType]) == MethodRB {
+		part, _, err = PartGraphRecursiveWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, k, targets, nil, options)
+	} else {
+		part, _, err = PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, k, targets, nil, options)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	for v, p := range part {
+		part[v] = machine[p]
+	}
+	return part, nparts, nil
+}
+
+// PartitionUnderCutBudget finds the largest number of partitions, up to
+// maxNparts, whose k-way edge cut stays within maxCut. It tries increasing
+// nparts and stops at the first count that exceeds the budget, since the cut
+// grows with nparts; this bounds communication while maximizing parallelism.
+// When even two partitions exceed the budget, the trivial single partition
+// (cut 0) is returned.
+func PartitionUnderCutBudget(g *Graph, maxCut int32, maxNparts int32, options []int32) (part []int32, nparts int32, cut int32, err error) {
+	if maxNparts < 1 {
+		return nil, 0, 0, fmt.Errorf("maxNparts must be at least 1, got %d", maxNparts)
+	}
+
+	part = make([]int32, g.NumVertices())
+	nparts = 1
+	for k := int32(2); k <= maxNparts; k++ {
+		p, c, err := PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, k, nil, nil, options)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if c > maxCut {
+			break
+		}
+		part, nparts, cut = p, k, c
+	}
+
+	return part, nparts, cut, nil
+}
+
+// PartitionExactBalance partitions g into nparts holding exactly the same
+// number of vertices, for layouts such as fixed-size tiles or systolic arrays.
+// g is padded with isolated virtual vertices of weight zero until its vertex
+// count is a multiple of nparts and partitioned with PartitionGraph, so METIS
+// balances the weights of the real vertices (g.Vwgt, or one per vertex).
+// Vertices are then moved, as by Rebalance, until every partition has the
+// same count; virtual vertices cost no cut to move, and the real vertices
+// moved may cost some of the weight balance.
+//
+// part holds the partition of the real vertices only, and padding is the
+// number of virtual vertices added. PaddingPerPartition tells how many of them
+// each partition absorbed.
+func PartitionExactBalance(g *Graph, nparts int32, options []int32) (part []int32, padding int, err error) {
+	if nparts < 1 {
+		return nil, 0, fmt.Errorf("nparts must be at least 1, got %d", nparts)
+	}
+
+	nvtxs := g.NumVertices()
+	nvirtual := 0
+	if r := nvtxs % int(nparts); r != 0 {
+		nvirtual = int(nparts) - r
+	}
+	padded := &Graph{
+		Xadj:   append([]int32(nil), g.Xadj...),
+		Adjncy: g.Adjncy,
+		Vwgt:   make([]int32, nvtxs+nvirtual),
+		Adjwgt: g.Adjwgt,
+	}
+	for v := 0; v < nvtxs; v++ {
+		padded.Vwgt[v] = 1
+		if g.Vwgt != nil {
+			padded.Vwgt[v] = g.Vwgt[v]
+		}
+	}
+	for i := 0; i < nvirtual; i++ {
+		padded.Xadj = append(padded.Xadj, padded.Xadj[len(padded.Xadj)-1])
+	}
+
+	part, _, err = PartitionGraph(padded, nparts, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Balance the vertex counts, which fills every partition exactly
+	rebalance(padded, nil, part, nparts, int64((nvtxs+nvirtual)/int(nparts)))
+
+	return part[:nvtxs:nvtxs], nvirtual, nil
+}
+
+// PaddingPerPartition returns, for a partition returned by
+// PartitionExactBalance along with its padding, the number of virtual
+// vertices each partition absorbed: partition p has that many fewer real
+// vertices than the fullest one, and callers filling fixed-size tiles leave
+// that many slots empty.
+func PaddingPerPartition(part []int32, nparts int32, padding int) []int {
+	size := (len(part) + padding) / int(nparts)
+	slots := make([]int, nparts)
+	for p := range slots {
+		slots[p] = size
+	}
+	for _, p := range part {
+		slots[p]--
+	}
+	return slots
+}
+
+// PartGraphKwayMinSize partitions g into at most nparts with k-way
+// partitioning such that every partition has at least minSize vertices, for
+// deployments where a process is only worth starting with enough work.
+// Partitions that come out smaller are merged into their neighbors with
+// MergeSmallPartitions, so actualNparts may be less than nparts. It fails if
+// the graph has fewer than minSize vertices, since no partition can then be
+// large enough.
+func PartGraphKwayMinSize(g *Graph, nparts int32, minSize int, options []int32) (part []int32, actualNparts int32, err error) {
+	if g.NumVertices() < minSize {
+		return nil, 0, fmt.Errorf("graph has %d vertices, fewer than the minimum partition size %d", g.NumVertices(), minSize)
+	}
+
+	part, _, err = PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	part, actualNparts = MergeSmallPartitions(g, part, nparts, minSize)
+	if actualNparts < nparts {
+		debugf("PartGraphKwayMinSize: merged %d partitions below %d vertices", nparts-actualNparts, minSize)
+	}
+	return part, actualNparts, nil
+}
+
+// PartitionByComponents partitions each connected component of g separately
+// and stitches the results into one partition vector with ids 0..k-1, where k
+// is at most nparts. Partitioning a disconnected graph as a whole tends to
+// spend partitions on tiny components; here the budget is divided between
+// components by vertex weight instead. With fewer components than nparts,
+// every component gets at least one partition and each further partition goes
+// to the component with the heaviest partitions so far. With at least nparts
+// components, whole components are packed onto the lightest partition,
+// heaviest first. A component never gets more partitions than vertices, so
+// k falls short of nparts only when g has fewer than nparts vertices.
+func PartitionByComponents(g *Graph, nparts int32, options []int32) (part []int32, err error) {
+	if nparts < 1 {
+		return nil, fmt.Errorf("nparts must be at least 1, got %d", nparts)
+	}
+
+	comp, ncomp := g.ConnectedComponents()
+	if ncomp <= 1 {
+		part, _, err = PartitionGraph(g, nparts, options)
+		return part, err
+	}
+
+	members := PartitionMembers(comp, ncomp)
+	weights := make([]int64, ncomp)
+	for v, c := range comp {
+		if g.Vwgt != nil {
+			weights[c] += int64(g.Vwgt[v])
+		} else {
+			weights[c]++
+		}
+	}
+
+	part = make([]int32, len(comp))
+
+	if ncomp >= nparts {
+		order := make([]int32, ncomp)
+		for c := range order {
+			order[c] = int32(c)
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return weights[order[a]] > weights[order[b]]
+		})
+
+		loads := make([]int64, nparts)
+		for _, c := range order {
+			lightest := int32(0)
+			for p := int32(1); p < nparts; p++ {
+				if loads[p] < loads[lightest] {
+					lightest = p
+				}
+			}
+			loads[lightest] += weights[c]
+			for _, v := range members[c] {
+				part[v] = lightest
+			}
+		}
+		return part, nil
+	}
+
+	budget := make([]int32, ncomp)
+	for c := range budget {
+		budget[c] = 1
+	}
+	for remaining := nparts - ncomp; remaining > 0; remaining-- {
+		best := int32(-1)
+		for c := int32(0); c < ncomp; c++ {
+			if budget[c] >= int32(len(members[c])) {
+				continue
+			}
+			if best < 0 || weights[c]*int64(budget[best]) > weights[best]*int64(budget[c]) {
+				best = c
+			}
+		}
+		if best < 0 {
+			break
+		}
+		budget[best]++
+	}
+
+	offset := int32(0)
+	for c := int32(0); c < ncomp; c++ {
+		if budget[c] > 1 {
+			subPart, _, err := PartitionGraph(g.Subgraph(members[c]), budget[c], options)
+			if err != nil {
+				return nil, fmt.Errorf("component %d: %w", c, err)
+			}
+			for i, v := range members[c] {
+				part[v] = offset + subPart[i]
+			}
+		} else {
+			for _, v := range members[c] {
+				part[v] = offset
+			}
+		}
+		offset += budget[c]
+	}
+
+	return part, nil
+}
+
+// PartGraphKwayHinted partitions g into nparts while biasing the result toward
+// a previous partition hint, for incremental updates where partitions should
+// stay stable between runs. METIS has no input for an initial partition, so
+// this is an approximation: edges joining two vertices that share a hint
+// partition have their weight multiplied by 1+strength before partitioning,
+// making cuts through former partitions expensive. strength 0 ignores the hint;
+// larger values trade edge cut for stability.
+//
+// Partition ids in the result are renumbered to overlap the hint as much as
+// possible. The returned edge cut is measured with the original weights of g,
+// and divergence is the fraction of vertices whose partition differs from the
+// hint.
+func PartGraphKwayHinted(g *Graph, nparts int32, hint []int32, strength float32, options []int32) (part []int32, edgecut int32, divergence float64, err error) {
+	nvtxs := g.NumVertices()
+	if len(hint) != nvtxs {
+		return nil, 0, 0, fmt.Errorf("hint length %d does not match %d vertices", len(hint), nvtxs)
+	}
+	for i, p := range hint {
+		if p < 0 || p >= nparts {
+			return nil, 0, 0, fmt.Errorf("hint[%d] = %d outside [0, %d)", i, p, nparts)
+		}
+	}
+	if strength < 0 {
+		return nil, 0, 0, fmt.Errorf("strength must be non-negative, got %g", strength)
+	}
+
+	scale := 1 + float64(strength)
+	adjwgt := make([]int32, len(g.Adjncy))
+	for i := 0; i < nvtxs; i++ {
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			w := int32(1)
+			if g.Adjwgt != nil {
+				w = g.Adjwgt[j]
+			}
+			if hint[i] == hint[g.Adjncy[j]] {
+				w = int32(math.Min(math.Round(float64(w)*scale), math.MaxInt32))
+			}
+			adjwgt[j] = w
+		}
+	}
+
+	part, _, err = PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, adjwgt, nparts, nil, nil, options)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	relabelToHint(part, hint, nparts)
+
+	moved := 0
+	for i := range part {
+		if part[i] != hint[i] {
+			moved++
+		}
+	}
+	if nvtxs > 0 {
+		divergence = float64(moved) / float64(nvtxs)
+	}
+
+	return part, CalculateEdgeCut(g, part), divergence, nil
+}
+
+// relabelToHint renumbers the partitions of part in place, greedily matching
+// each to the hint partition it overlaps most
+func relabelToHint(part, hint []int32, nparts int32) {
+	overlap := make([]int, nparts*nparts)
+	for i, p := range part {
+		overlap[p*nparts+hint[i]]++
+	}
+
+	pairs := make([]int32, len(overlap))
+	for k := range pairs {
+		pairs[k] = int32(k)
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return overlap[pairs[a]] > overlap[pairs[b]]
+	})
+
+	label := make([]int32, nparts)
+	used := make([]bool, nparts)
+	for p := range label {
+		label[p] = -1
+	}
+	for _, k := range pairs {
+		p, h := k/nparts, k%nparts
+		if label[p] < 0 && !used[h] {
+			label[p], used[h] = h, true
+		}
+	}
+
+	for i, p := range part {
+		part[i] = label[p]
+	}
+}
+
+// Constants of the memory model used by EstimateMemoryBytes, in idx_t words
+const (
+	memIdxBytes        = 4 // The bindings pass int32 arrays as idx_t
+	memHierarchyFactor = 2 // Each coarsening level roughly halves the graph
+	memVertexWorkKway  = 12
+	memVertexWorkRB    = 8
+	memEdgeWorkKway    = 4 // Neighbor-partition info used by k-way refinement
+)
+
+// EstimateMemoryBytes estimates the peak memory METIS needs to partition a
+// graph of nvtxs vertices and nedges undirected edges into nparts with the
+// given method (MethodRB or MethodKway). It follows the O(n+m) behavior of
+// METIS: the input graph is copied with vertex and edge weights, the
+// multilevel hierarchy of coarser graphs about doubles that, and each level
+// carries per-vertex work arrays. K-way refinement adds per-edge neighbor
+// information and an nparts by nparts table.
+//
+// This is an estimate for capacity planning, not a bound: actual usage depends
+// on the METIS version, its idx_t width and how quickly the graph coarsens. It
+// excludes the memory held by the caller's own input and output slices.
+func EstimateMemoryBytes(nvtxs, nedges int64, nparts int32, method PType) int64 {
+	if nvtxs <= 0 {
+		return 0
+	}
+	adjacency := 2 * nedges
+
+	// xadj, adjncy, vwgt and adjwgt over the whole multilevel hierarchy
+	words := memHierarchyFactor * (2*nvtxs + 1 + 2*adjacency)
+
+	if method == MethodRB {
+		words += memHierarchyFactor * memVertexWorkRB * nvtxs
+	} else {
+		words += memHierarchyFactor*memVertexWorkKway*nvtxs +
+			memEdgeWorkKway*adjacency +
+			int64(nparts)*int64(nparts)
+	}
+
+	return words * memIdxBytes
+}
+
+// Partition partitions g into nparts using its vertex and edge weights,
+// configured by functional options such as WithSeed, WithObjective, WithMethod,
+// WithImbalance and WithTargetWeights. Without options it runs k-way
+// partitioning with the METIS defaults.
+func Partition(g *Graph, nparts int32, opts ...Option) (*PartitionResult, error) {
+	s, err := newPartitionSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.mustBeConnected {
+		if err := g.WeaklyConnectedCheck(); err != nil {
+			return nil, err
+		}
+	}
+	if s.checkEdgeWeights && g.Adjwgt != nil {
+		if err := ValidateEdgeWeights(g.Xadj, g.Adjncy, g.Adjwgt); err != nil {
+			return nil, err
+		}
+	}
+
+	part := make([]int32, numVertices(g.Xadj))
+	recursive := PType(s.options[OptionPType]) == MethodRB
+	objval, err := partGraph(recursive, g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, s.tpwgts, nil, s.options, part)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartitionResult{Part: part, Objval: objval}, nil
+}
+
+// PartGraphKwayBothObjectives partitions g with k-way partitioning, minimizing
+// the objective selected by OptionObjType, and reports both the edge cut and
+// the communication volume of the result. The metric METIS did not optimize
+// comes from one Metrics pass over the graph instead of a second partitioning.
+func PartGraphKwayBothObjectives(g *Graph, nparts int32, options []int32) (part []int32, cut int32, vol int32, err error) {
+	part, _, err = PartGraphKwayWeighted(g.Xadj, g.Adjncy, g.Vwgt, g.Adjwgt, nparts, nil, nil, options)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	m := Metrics(g, part, nparts)
+	return part, m.EdgeCut, m.CommVolume, nil
+}
+
+// BisectTree is a node of the tree of recursive bisections built by
+// BisectionTree. Leaves hold the vertices of their part; interior nodes hold
+// the two halves their vertices were split into. All vertex ids refer to the
+// original graph.
+type BisectTree struct {
+	Vertices []int32       // Vertices of the part (leaves only)
+	Children []*BisectTree // The two halves (nil for leaves)
+}
+
+// IsLeaf reports whether the node is an unsplit part
+func (t *BisectTree) IsLeaf() bool {
+	return len(t.Children) == 0
+}
+
+// Leaves returns the vertex sets of the leaves from left to right
+func (t *BisectTree) Leaves() [][]int32 {
+	if t.IsLeaf() {
+		return [][]int32{t.Vertices}
+	}
+	var leaves [][]int32
+	for _, c := range t.Children {
+		leaves = append(leaves, c.Leaves()...)
+	}
+	return leaves
+}
+
+// BisectionTree recursively bisects g to the given depth and returns the tree
+// of bisections rather than a flat partition, for mapping onto hierarchical
+// topologies such as binary trees of networks or nested communicators. Each
+// split is a 2-way PartGraphRecursive of the subgraph induced by the part,
+// with its vertex and edge weights. The tree is complete: it has exactly
+// 2^depth leaves, which together hold every vertex once. A part of fewer than
+// two vertices is split into itself and an empty leaf. Since the tree is
+// complete, depth may not exceed ceil(log2(nvtxs)), the first depth with at
+// least as many leaves as vertices, which keeps the tree under 4*nvtxs nodes.
+func BisectionTree(g *Graph, depth int32, options []int32) (*BisectTree, error) {
+	maxDepth := int32(0)
+	for 1<<uint(maxDepth) < g.NumVertices() {
+		maxDepth++
+	}
+	if depth < 0 || depth > maxDepth {
+		return nil, fmt.Errorf("depth must be in [0, %d] for %d vertices, got %d", maxDepth, g.NumVertices(), depth)
+	}
+	vertices := make([]int32, g.NumVertices())
+	for i := range vertices {
+		vertices[i] = int32(i)
+	}
+	return bisect(g, vertices, depth, options)
+}
+
+// bisect builds the bisection tree of the subgraph induced by vertices
+func bisect(g *Graph, vertices []int32, depth int32, options []int32) (*BisectTree, error) {
+	if depth == 0 {
+		return &BisectTree{Vertices: vertices}, nil
+	}
+
+	var halves [2][]int32
+	if len(vertices) < 2 {
+		halves[0] = vertices
+	} else {
+		sub := g.Subgraph(vertices)
+		part, _, err := PartGraphRecursiveWeighted(sub.Xadj, sub.Adjncy, sub.Vwgt, sub.Adjwgt, 2, nil, nil, options)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range part {
+			halves[p] = append(halves[p], vertices[i])
+		}
+	}
+
+	node := &BisectTree{}
+	for _, half := range halves {
+		child, err := bisect(g, half, depth-1, options)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
@@ -0,0 +1,498 @@
+package metis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartGraphKwayRelaxing(t *testing.T) {
+	xadj, adjncy := createGridGraph(10, 10)
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	part, cut, imbalance, err := PartGraphKwayRelaxing(g, 4, 1.01, 1.2, 0.05, opts)
+	require.NoError(t, err)
+	assert.Len(t, part, 100)
+	assert.Equal(t, CalculateEdgeCut(g, part), cut)
+	assert.InDelta(t, 1.01, imbalance, 1e-9)
+
+	// The caller's options are left untouched
+	assert.Equal(t, int32(-1), opts[OptionUFactor])
+
+	_, _, _, err = PartGraphKwayRelaxing(g, 4, 1.01, 1.2, 0, opts)
+	assert.Error(t, err)
+
+	_, _, _, err = PartGraphKwayRelaxing(g, 4, 1.2, 1.01, 0.05, nil)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInput))
+}
+
+func TestPartitionDirected(t *testing.T) {
+	// A directed ring 0->1->...->7->0
+	xadj := make([]int32, 9)
+	adjncy := make([]int32, 8)
+	for v := int32(0); v < 8; v++ {
+		xadj[v+1] = v + 1
+		adjncy[v] = (v + 1) % 8
+	}
+
+	part, cut, err := PartitionDirected(xadj, adjncy, nil, 2, WeightPolicyMax, nil)
+	require.NoError(t, err)
+	require.Len(t, part, 8)
+	g, _ := Symmetrize(xadj, adjncy, nil, WeightPolicyMax)
+	assert.Equal(t, CalculateEdgeCut(g, part), cut)
+
+	_, _, err = PartitionDirected(xadj, adjncy, nil, 2, WeightPolicy(9), nil)
+	assert.Error(t, err)
+}
+
+func TestCapacityTargetWeights(t *testing.T) {
+	tpwgts, err := CapacityTargetWeights([]float64{3.0, 2.0, 2.0, 1.0})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float32{0.375, 0.25, 0.25, 0.125}, tpwgts, 1e-6)
+
+	sum := float32(0)
+	for _, w := range tpwgts {
+		sum += w
+	}
+	assert.InDelta(t, 1.0, sum, 1e-6)
+
+	_, err = CapacityTargetWeights([]float64{1, -1})
+	assert.Error(t, err)
+	_, err = CapacityTargetWeights([]float64{0, 0})
+	assert.Error(t, err)
+	_, err = CapacityTargetWeights(nil)
+	assert.Error(t, err)
+}
+
+func TestPartitionForCluster(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	// The idle machine 1 is skipped and the partitions keep machine indices
+	part, nparts, err := PartitionForCluster(g, []float64{2, 0, 1, 1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), nparts)
+	require.Len(t, part, 64)
+	counts := make([]int, nparts)
+	for _, p := range part {
+		counts[p]++
+	}
+	assert.Zero(t, counts[1])
+	assert.Positive(t, counts[0])
+	assert.Positive(t, counts[2])
+	assert.Positive(t, counts[3])
+
+	// A single usable machine takes everything
+	part, nparts, err = PartitionForCluster(g, []float64{0, 1.5}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), nparts)
+	for _, p := range part {
+		assert.Equal(t, int32(1), p)
+	}
+
+	_, _, err = PartitionForCluster(g, []float64{0, 0}, nil)
+	assert.Error(t, err)
+}
+
+func TestPartitionUnderCutBudget(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	part, nparts, cut, err := PartitionUnderCutBudget(g, 20, 8, opts)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, nparts, int32(2))
+	assert.LessOrEqual(t, cut, int32(20))
+	assert.Equal(t, CalculateEdgeCut(g, part), cut)
+
+	// A budget smaller than any bisection keeps everything together
+	part, nparts, cut, err = PartitionUnderCutBudget(g, 0, 8, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), nparts)
+	assert.Equal(t, int32(0), cut)
+	assert.Equal(t, make([]int32, 64), part)
+
+	_, _, _, err = PartitionUnderCutBudget(g, 10, 0, opts)
+	assert.Error(t, err)
+}
+
+func TestPartitioner(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	t.Run("ReusedBuffer", func(t *testing.T) {
+		p := &Partitioner{Options: opts}
+
+		first, err := p.Partition(g, 2)
+		require.NoError(t, err)
+		require.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 2, first.Objval, first.Part))
+		kept := first.Detach()
+
+		second, err := p.Partition(g, 4)
+		require.NoError(t, err)
+
+		// The first result was overwritten in place; the detached copy was not
+		assert.Equal(t, second.Part, first.Part)
+		assert.Same(t, &first.Part[0], &second.Part[0])
+		assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 2, kept.Objval, kept.Part))
+	})
+
+	t.Run("CopyResult", func(t *testing.T) {
+		p := &Partitioner{Options: opts, CopyResult: true}
+
+		first, err := p.Partition(g, 2)
+		require.NoError(t, err)
+		second, err := p.Partition(g, 4)
+		require.NoError(t, err)
+
+		assert.NotSame(t, &first.Part[0], &second.Part[0])
+		assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 2, first.Objval, first.Part))
+		assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 4, second.Objval, second.Part))
+	})
+
+	t.Run("InvalidGraph", func(t *testing.T) {
+		p := &Partitioner{}
+		_, err := p.Partition(NewGraph(nil, nil), 2)
+		assert.Error(t, err)
+	})
+}
+
+func TestPartitionByComponents(t *testing.T) {
+	// A 4x4 grid (vertices 0-15) next to a disjoint 2x2 grid (vertices 16-19)
+	xadj, adjncy := createGridGraph(4, 4)
+	xadj2, adjncy2 := createGridGraph(2, 2)
+	base := int32(len(adjncy))
+	for _, x := range xadj2[1:] {
+		xadj = append(xadj, base+x)
+	}
+	for _, u := range adjncy2 {
+		adjncy = append(adjncy, u+16)
+	}
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	t.Run("ProportionalBudget", func(t *testing.T) {
+		part, err := PartitionByComponents(g, 5, opts)
+		require.NoError(t, err)
+		require.Len(t, part, 20)
+
+		// The large component receives four partitions and the small one the fifth
+		for v := 0; v < 16; v++ {
+			assert.Less(t, part[v], int32(4))
+		}
+		for v := 16; v < 20; v++ {
+			assert.Equal(t, int32(4), part[v])
+		}
+		assert.NoError(t, VerifyBalance(part, nil, 5, 300))
+	})
+
+	t.Run("MoreComponentsThanParts", func(t *testing.T) {
+		// Five isolated vertices packed by weight onto two partitions
+		isolated := &Graph{
+			Xadj: []int32{0, 0, 0, 0, 0, 0},
+			Vwgt: []int32{5, 4, 3, 2, 2},
+		}
+		part, err := PartitionByComponents(isolated, 2, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []int32{0, 1, 1, 0, 0}, part)
+	})
+
+	t.Run("Connected", func(t *testing.T) {
+		part, err := PartitionByComponents(NewGraph(createGridGraph(4, 4)), 2, opts)
+		require.NoError(t, err)
+		assert.Len(t, part, 16)
+	})
+
+	_, err := PartitionByComponents(g, 0, opts)
+	assert.Error(t, err)
+}
+
+func TestPartGraphKwayHinted(t *testing.T) {
+	g := NewGraph(createGridGraph(8, 8))
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	// The hint splits the grid into top and bottom halves, with the labels
+	// reversed relative to vertex order
+	hint := make([]int32, 64)
+	for v := 0; v < 32; v++ {
+		hint[v] = 1
+	}
+
+	part, cut, divergence, err := PartGraphKwayHinted(g, 2, hint, 10, opts)
+	require.NoError(t, err)
+	require.Len(t, part, 64)
+	assert.Equal(t, CalculateEdgeCut(g, part), cut)
+
+	moved := 0
+	for v := range part {
+		if part[v] != hint[v] {
+			moved++
+		}
+	}
+	assert.InDelta(t, float64(moved)/64, divergence, 1e-12)
+
+	// Cutting along the hint is also a minimum cut, so a strong hint is kept
+	assert.Equal(t, hint, part)
+	assert.Equal(t, int32(8), cut)
+	assert.Zero(t, divergence)
+
+	_, _, _, err = PartGraphKwayHinted(g, 2, hint[:10], 1, opts)
+	assert.Error(t, err)
+	_, _, _, err = PartGraphKwayHinted(g, 1, hint, 1, opts)
+	assert.Error(t, err)
+	_, _, _, err = PartGraphKwayHinted(g, 2, hint, -1, opts)
+	assert.Error(t, err)
+}
+
+func TestRelabelToHint(t *testing.T) {
+	part := []int32{2, 2, 0, 0, 1, 1}
+	hint := []int32{0, 0, 1, 1, 1, 2}
+	relabelToHint(part, hint, 3)
+	assert.Equal(t, []int32{0, 0, 1, 1, 2, 2}, part)
+}
+
+func TestEstimateMemoryBytes(t *testing.T) {
+	assert.Zero(t, EstimateMemoryBytes(0, 0, 4, PTypeKway))
+
+	small := EstimateMemoryBytes(1000, 2000, 4, PTypeKway)
+	assert.Greater(t, small, int64(0))
+
+	// Linear in the graph size, with k-way needing more than bisection
+	large := EstimateMemoryBytes(1000000, 2000000, 4, PTypeKway)
+	assert.InDelta(t, 1000, float64(large)/float64(small), 1)
+	assert.Greater(t, large, EstimateMemoryBytes(1000000, 2000000, 4, PTypeRB))
+
+	// At least the input graph itself must fit
+	assert.Greater(t, large, int64(4*(1000000+1+4000000)))
+}
+
+func TestPartition(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	res, err := Partition(g, 4)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 4, res.Objval, res.Part))
+
+	res, err = Partition(g, 4,
+		WithSeed(42),
+		WithMethod(PTypeRB),
+		WithObjective(ObjTypeCut),
+		WithImbalance(1.05),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 4, res.Objval, res.Part))
+
+	// Seeded runs are reproducible
+	again, err := Partition(g, 4, WithSeed(42), WithMethod(PTypeRB), WithObjective(ObjTypeCut), WithImbalance(1.05))
+	require.NoError(t, err)
+	assert.Equal(t, res.Part, again.Part)
+
+	res, err = Partition(g, 2, WithTargetWeights([]float32{0.25, 0.75}))
+	require.NoError(t, err)
+	counts := [2]int{}
+	for _, p := range res.Part {
+		counts[p]++
+	}
+	assert.InDelta(t, 16, counts[0], 3)
+
+	for name, opt := range map[string]Option{
+		"Objective": WithObjective(7),
+		"Method":    WithMethod(7),
+		"Imbalance": WithImbalance(0.5),
+		"Targets":   WithTargetWeights([]float32{1}),
+	} {
+		_, err := Partition(g, 2, opt)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPartGraphKwayBothObjectives(t *testing.T) {
+	g := NewGraph(createGridGraph(10, 10))
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	for _, objective := range []int32{ObjTypeCut, ObjTypeVol} {
+		opts[OptionObjType] = objective
+		part, cut, vol, err := PartGraphKwayBothObjectives(g, 4, opts)
+		require.NoError(t, err)
+		assert.Equal(t, CalculateEdgeCut(g, part), cut)
+		assert.Equal(t, Metrics(g, part, 4).CommVolume, vol)
+	}
+}
+
+func TestPartitionMustBeConnected(t *testing.T) {
+	// Two disjoint copies of a 4x4 grid
+	grid := NewGraph(createGridGraph(4, 4))
+	g := &Graph{Xadj: append([]int32(nil), grid.Xadj...), Adjncy: append([]int32(nil), grid.Adjncy...)}
+	for v := 1; v < len(grid.Xadj); v++ {
+		g.Xadj = append(g.Xadj, grid.Xadj[v]+int32(len(grid.Adjncy)))
+	}
+	for _, u := range grid.Adjncy {
+		g.Adjncy = append(g.Adjncy, u+16)
+	}
+
+	_, err := Partition(g, 2)
+	require.NoError(t, err)
+	_, err = Partition(g, 2, WithMustBeConnected())
+	assert.ErrorIs(t, err, ErrDisconnected)
+
+	p := &Partitioner{MustBeConnected: true}
+	_, err = p.Partition(g, 2)
+	assert.ErrorIs(t, err, ErrDisconnected)
+	_, err = p.Partition(grid, 2)
+	assert.NoError(t, err)
+}
+
+func TestPartitionExactBalance(t *testing.T) {
+	xadj, adjncy := createGridGraph(5, 5)
+	g := NewGraph(xadj, adjncy)
+	g.Vwgt = make([]int32, 25)
+	for v := range g.Vwgt {
+		g.Vwgt[v] = int32(1 + v%3)
+	}
+
+	// Every partition holds 7 vertices, real or virtual
+	part, padding, err := PartitionExactBalance(g, 4, nil)
+	require.NoError(t, err)
+	require.Len(t, part, 25)
+	assert.Equal(t, 3, padding)
+	counts := make([]int, 4)
+	for _, p := range part {
+		counts[p]++
+	}
+	slots := PaddingPerPartition(part, 4, padding)
+	require.Len(t, slots, 4)
+	total := 0
+	for p := range counts {
+		assert.Equal(t, 7, counts[p]+slots[p], "partition %d", p)
+		total += slots[p]
+	}
+	assert.Equal(t, 3, total)
+	assert.Len(t, g.Xadj, 26)
+
+	// No padding when the count already divides
+	part, padding, err = PartitionExactBalance(g, 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, padding)
+	assert.Equal(t, []int{0, 0, 0, 0, 0}, PaddingPerPartition(part, 5, padding))
+	counts = make([]int, 5)
+	for _, p := range part {
+		counts[p]++
+	}
+	assert.Equal(t, []int{5, 5, 5, 5, 5}, counts)
+
+	_, _, err = PartitionExactBalance(g, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestPartGraphKwayMinSize(t *testing.T) {
+	xadj, adjncy := createGridGraph(6, 6)
+	g := NewGraph(xadj, adjncy)
+
+	part, nparts, err := PartGraphKwayMinSize(g, 4, 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), nparts)
+	require.Len(t, part, 36)
+
+	// Eight partitions of 36 vertices cannot all reach 6
+	part, nparts, err = PartGraphKwayMinSize(g, 8, 6, nil)
+	require.NoError(t, err)
+	assert.Less(t, nparts, int32(8))
+	counts := make([]int, nparts)
+	for _, p := range part {
+		counts[p]++
+	}
+	for p, c := range counts {
+		assert.GreaterOrEqual(t, c, 6, "partition %d", p)
+	}
+
+	_, _, err = PartGraphKwayMinSize(g, 2, 37, nil)
+	assert.Error(t, err)
+}
+
+func TestPartitionByID(t *testing.T) {
+	// Two triangles of named vertices joined by c-d; "f" only appears as a neighbor
+	edges := map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c", "b"},
+		"c": {"d"},
+		"d": {"e", "f"},
+		"e": {"f", "d"},
+	}
+
+	part, err := PartitionByID(edges, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, part, 6)
+	for k, p := range part {
+		assert.True(t, p == 0 || p == 1, "key %s", k)
+	}
+
+	// Sparse integer ids work the same way
+	sparse, err := PartitionByID(map[int64][]int64{1000: {7}, 7: {42}, 42: {1000}}, 3, nil)
+	require.NoError(t, err)
+	assert.Len(t, sparse, 3)
+
+	_, err = PartitionByID(edges, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestBisectionTree(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	for depth := int32(0); depth <= 3; depth++ {
+		tree, err := BisectionTree(g, depth, nil)
+		require.NoError(t, err)
+		leaves := tree.Leaves()
+		require.Len(t, leaves, 1<<depth)
+
+		// The leaves partition the vertices
+		seen := make([]int, 64)
+		for _, leaf := range leaves {
+			for _, v := range leaf {
+				seen[v]++
+			}
+		}
+		for v, n := range seen {
+			assert.Equal(t, 1, n, "vertex %d at depth %d", v, depth)
+		}
+	}
+
+	// Uneven halves are padded with empty leaves
+	path := NewGraph([]int32{0, 1, 3, 4}, []int32{1, 0, 2, 1})
+	tree, err := BisectionTree(path, 2, nil)
+	require.NoError(t, err)
+	leaves := tree.Leaves()
+	require.Len(t, leaves, 4)
+	total := 0
+	for _, leaf := range leaves {
+		total += len(leaf)
+	}
+	assert.Equal(t, 3, total)
+
+	// Depth beyond ceil(log2(nvtxs)) is rejected up front rather than
+	// building 2^depth leaves
+	_, err = BisectionTree(path, 3, nil)
+	assert.Error(t, err)
+	_, err = BisectionTree(path, 30, nil)
+	assert.Error(t, err)
+	_, err = BisectionTree(g, 7, nil)
+	assert.Error(t, err)
+	_, err = BisectionTree(g, -1, nil)
+	assert.Error(t, err)
+}
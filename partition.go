@@ -0,0 +1,1028 @@
+package metis
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CommunicationPlan returns the halo-exchange send schedule for a partitioned
+// graph: plan[from][to] lists, in increasing order, the vertices owned by
+// partition from that have at least one neighbor in partition to. These are
+// exactly the boundary values partition from must send to partition to.
+// Partitions with no boundary vertices have no entry in the plan.
+func CommunicationPlan(g *Graph, part []int32, nparts int32) map[int32]map[int32][]int32 {
+	plan := make(map[int32]map[int32][]int32)
+	// sentTo[q] holds the last vertex (+1) already scheduled for partition q
+	sentTo := make([]int32, nparts)
+	nvtxs := g.NumVertices()
+
+	for i := 0; i < nvtxs; i++ {
+		from := part[i]
+		for j := g.Xadj[i]; j < g.Xadj[i+1]; j++ {
+			to := part[g.Adjncy[j]]
+			if to == from || sentTo[to] == int32(i+1) {
+				continue
+			}
+			sentTo[to] = int32(i + 1)
+
+			if plan[from] == nil {
+				plan[from] = make(map[int32][]int32)
+			}
+			plan[from][to] = append(plan[from][to], int32(i))
+		}
+	}
+
+	return plan
+}
+
+// Balance tolerances used by VerifyPartition
+const (
+	verifyBalanceFactor     = 1.10 // Fewer than 16 partitions
+	verifyBalanceFactorMany = 1.20 // 16 or more partitions
+)
+
+// ValidatePartition checks that part is safe to pass to the metric functions
+// of this package with nparts: every entry must lie in [0, nparts), otherwise
+// the error names the first vertex outside that range. Empty partitions are
+// valid, since METIS may leave partitions empty, but they are reported through
+// the Logger as a warning because they usually skew balance metrics.
+func ValidatePartition(part []int32, nparts int32) error {
+	if nparts < 1 {
+		return fmt.Errorf("nparts must be at least 1, got %d", nparts)
+	}
+
+	used := make([]bool, nparts)
+	for v, p := range part {
+		if p < 0 || p >= nparts {
+			return fmt.Errorf("vertex %d assigned to partition %d, outside [0, %d)", v, p, nparts)
+		}
+		used[p] = true
+	}
+
+	var empty []int32
+	for p, u := range used {
+		if !u {
+			empty = append(empty, int32(p))
+		}
+	}
+	if len(empty) > 0 {
+		warnf("ValidatePartition: %d of %d partitions are empty: %v", len(empty), nparts, empty)
+	}
+	return nil
+}
+
+// VerifyPartition checks a partition returned by the graph partitioning
+// functions and returns an error describing the first check that fails:
+//   - every entry of part is in [0, nparts) and the highest partition,
+//     nparts-1, is used
+//   - the edge cut recomputed from the graph equals reportedCut
+//   - the heaviest partition is within 10% of the average weight (20% for
+//     16 or more partitions)
+//
+// vwgt and adjwgt may be nil for unit weights. For a check that follows the
+// exact ufactor definition used by METIS, see VerifyBalance.
+func VerifyPartition(xadj, adjncy, vwgt, adjwgt []int32, nparts, reportedCut int32, part []int32) error {
+	nvtxs := len(xadj) - 1
+	if len(part) != nvtxs {
+		return fmt.Errorf("partition has %d entries for %d vertices", len(part), nvtxs)
+	}
+
+	maxPart := int32(-1)
+	for i, p := range part {
+		if p < 0 || p >= nparts {
+			return fmt.Errorf("vertex %d assigned to partition %d, outside [0, %d)", i, p, nparts)
+		}
+		if p > maxPart {
+			maxPart = p
+		}
+	}
+	if maxPart != nparts-1 {
+		return fmt.Errorf("highest partition used is %d, expected %d", maxPart, nparts-1)
+	}
+
+	pwgts := make([]int64, nparts)
+	cut := int64(0)
+	for i := 0; i < nvtxs; i++ {
+		if vwgt != nil {
+			pwgts[part[i]] += int64(vwgt[i])
+		} else {
+			pwgts[part[i]]++
+		}
+		for j := xadj[i]; j < xadj[i+1]; j++ {
+			if part[i] != part[adjncy[j]] {
+				if adjwgt != nil {
+					cut += int64(adjwgt[j])
+				} else {
+					cut++
+				}
+			}
+		}
+	}
+
+	// Each cut edge is seen from both endpoints
+	if cut != 2*int64(reportedCut) {
+		return fmt.Errorf("edge cut mismatch: computed %d, reported %d", cut/2, reportedCut)
+	}
+
+	total, heaviest := int64(0), int64(0)
+	for _, w := range pwgts {
+		total += w
+		if w > heaviest {
+			heaviest = w
+		}
+	}
+
+	factor := verifyBalanceFactor
+	if nparts >= 16 {
+		factor = verifyBalanceFactorMany
+	}
+	if float64(nparts)*float64(heaviest) > factor*float64(total) {
+		return fmt.Errorf("partition imbalance %.3f exceeds %.2f",
+			float64(nparts)*float64(heaviest)/float64(total), factor)
+	}
+
+	return nil
+}
+
+// VerifyBalance checks that a partition respects a METIS ufactor imbalance
+// tolerance. METIS defines the imbalance of partition p as
+// nparts*pwgts[p]/total, and ufactor as (imbalance-1)*1000, so a ufactor of 30
+// allows every partition to be at most 3% heavier than the average. vwgt may be
+// nil for unit vertex weights.
+//
+// An error naming the first partition that exceeds the tolerance is returned.
+// Note that METIS itself treats the tolerance as a goal, and may exceed it on
+// small or coarse-grained inputs where vertices cannot be split evenly.
+func VerifyBalance(part, vwgt []int32, nparts int32, ufactor int32) error {
+	pwgts := make([]int64, nparts)
+	total := int64(0)
+
+	for i, p := range part {
+		if p < 0 || p >= nparts {
+			return fmt.Errorf("vertex %d assigned to partition %d, outside [0, %d)", i, p, nparts)
+		}
+		w := int64(1)
+		if vwgt != nil {
+			w = int64(vwgt[i])
+		}
+		pwgts[p] += w
+		total += w
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	limit := 1 + float64(ufactor)/1000
+	for p, w := range pwgts {
+		imbalance := float64(nparts) * float64(w) / float64(total)
+		if imbalance > limit {
+			return fmt.Errorf("partition %d has imbalance %.3f, exceeding %.3f (ufactor %d)", p, imbalance, limit, ufactor)
+		}
+	}
+
+	return nil
+}
+
+// HyperedgeCut evaluates a vertex partition against the nets of the original
+// hypergraph, typically after partitioning its clique expansion. Net h spans
+// the vertices hind[hptr[h]:hptr[h+1]]. It returns the cut-net metric (the
+// number of nets whose vertices span more than one partition) and the
+// connectivity-minus-one metric, the sum over nets of λ−1 where λ is the
+// number of distinct partitions a net touches.
+func HyperedgeCut(nhedges int32, hptr, hind []int32, part []int32) (cutNets int32, connectivityMinusOne int32) {
+	nparts := int32(0)
+	for _, p := range part {
+		if p+1 > nparts {
+			nparts = p + 1
+		}
+	}
+
+	// seen[p] holds the last net (+1) that touched partition p
+	seen := make([]int32, nparts)
+	for h := int32(0); h < nhedges; h++ {
+		lambda := int32(0)
+		for j := hptr[h]; j < hptr[h+1]; j++ {
+			p := part[hind[j]]
+			if seen[p] != h+1 {
+				seen[p] = h + 1
+				lambda++
+			}
+		}
+		if lambda > 1 {
+			cutNets++
+			connectivityMinusOne += lambda - 1
+		}
+	}
+
+	return cutNets, connectivityMinusOne
+}
+
+// QuotientGraph returns the graph whose vertices are the partitions of g. The
+// vertex weight of partition p is the total weight of its vertices and the
+// edge between two partitions carries the total weight of the cut edges
+// joining them.
+func QuotientGraph(g *Graph, part []int32, nparts int32) *Graph {
+	return g.Contract(part, nparts)
+}
+
+// PartitionMembers returns the inverse of part: for each partition, the
+// increasing list of vertices assigned to it. Entries of part outside
+// [0, nparts) are ignored. Building it once is much cheaper than scanning part
+// for every partition.
+func PartitionMembers(part []int32, nparts int32) [][]int32 {
+	counts := make([]int32, nparts)
+	for _, p := range part {
+		if p >= 0 && p < nparts {
+			counts[p]++
+		}
+	}
+
+	// Carve all lists out of one allocation
+	backing := make([]int32, 0, len(part))
+	members := make([][]int32, nparts)
+	offset := int32(0)
+	for p, c := range counts {
+		members[p] = backing[offset : offset : offset+c]
+		offset += c
+	}
+
+	for v, p := range part {
+		if p >= 0 && p < nparts {
+			members[p] = append(members[p], int32(v))
+		}
+	}
+
+	return members
+}
+
+// PartitionMetrics holds the quality measures of a partition computed by
+// Metrics
+type PartitionMetrics struct {
+	EdgeCut          int32 // Total weight of the cut edges, the METIS cut objective
+	CommVolume       int32 // Total communication volume, the METIS vol objective
+	BoundaryVertices int32 // Vertices with a neighbor in another partition
+}
+
+// Metrics computes the edge cut and communication volume of a partition in a
+// single pass over the graph. The communication volume follows the METIS
+// definition with unit vertex sizes: every vertex counts once for each
+// distinct other partition among its neighbors.
+func Metrics(g *Graph, part []int32, nparts int32) PartitionMetrics {
+	var m PartitionMetrics
+
+	// seen[p] == v+1 marks partition p as already counted for vertex v
+	seen := make([]int32, nparts)
+	for v := 0; v < g.NumVertices(); v++ {
+		boundary := false
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			p := part[g.Adjncy[j]]
+			if p == part[v] {
+				continue
+			}
+			boundary = true
+			if g.Adjwgt != nil {
+				m.EdgeCut += g.Adjwgt[j]
+			} else {
+				m.EdgeCut++
+			}
+			if seen[p] != int32(v)+1 {
+				seen[p] = int32(v) + 1
+				m.CommVolume++
+			}
+		}
+		if boundary {
+			m.BoundaryVertices++
+		}
+	}
+
+	// Every cut edge was seen from both ends
+	m.EdgeCut /= 2
+	return m
+}
+
+// PartitionDensities returns the internal edge density of every partition:
+// the number of edges with both ends inside it divided by the n(n-1)/2 edges
+// its n vertices could have. Low densities point at partitions that are
+// poorly cohesive, which matters when partitioning serves as community
+// detection. Edge weights are ignored, g must be free of self-loops and
+// parallel edges (see Normalize), and partitions of fewer than two vertices
+// have density 0.
+func (g *Graph) PartitionDensities(part []int32, nparts int32) []float64 {
+	// Cut edges are counted without weights
+	structure := &Graph{Xadj: g.Xadj, Adjncy: g.Adjncy}
+
+	densities := make([]float64, nparts)
+	for p, members := range PartitionMembers(part, nparts) {
+		n := int64(len(members))
+		if n < 2 {
+			continue
+		}
+		degrees := int64(0)
+		for _, v := range members {
+			degrees += int64(g.Xadj[v+1] - g.Xadj[v])
+		}
+		internal := (degrees - int64(structure.SubsetCut(members))) / 2
+		densities[p] = float64(internal) / float64(n*(n-1)/2)
+	}
+	return densities
+}
+
+// Modularity returns Newman's modularity Q of part over the weighted graph:
+// the fraction of edge weight inside partitions minus the fraction expected if
+// edges were placed at random with the same weighted degrees,
+//
+//	Q = sum over partitions c of in(c)/2m - (tot(c)/2m)^2
+//
+// where in(c) is the weight of the adjacency entries with both ends in c, tot(c)
+// the total weighted degree of c and 2m the weight of all adjacency entries.
+// Q ranges from -1/2 to 1; values above about 0.3 indicate clear community
+// structure. A graph without edges has modularity 0.
+func (g *Graph) Modularity(part []int32) float64 {
+	inside := make(map[int32]float64)
+	degree := make(map[int32]float64)
+	total := 0.0
+	for u := 0; u+1 < len(g.Xadj); u++ {
+		for j := g.Xadj[u]; j < g.Xadj[u+1]; j++ {
+			w := 1.0
+			if g.Adjwgt != nil {
+				w = float64(g.Adjwgt[j])
+			}
+			total += w
+			degree[part[u]] += w
+			if part[g.Adjncy[j]] == part[u] {
+				inside[part[u]] += w
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	q := 0.0
+	for c, d := range degree {
+		q += inside[c]/total - (d/total)*(d/total)
+	}
+	return q
+}
+
+// Conductance returns the conductance of every partition: the weight of its
+// cut edges divided by the smaller of its volume and the volume of the rest of
+// the graph, where the volume of a vertex set is its total weighted degree.
+// Values range from 0 to 1, and low conductance marks a well separated
+// community. A partition whose volume or complementary volume is zero, such
+// as an empty one, has conductance 0.
+func (g *Graph) Conductance(part []int32, nparts int32) []float64 {
+	cut := make([]float64, nparts)
+	volume := make([]float64, nparts)
+	total := 0.0
+	for u := 0; u+1 < len(g.Xadj); u++ {
+		for j := g.Xadj[u]; j < g.Xadj[u+1]; j++ {
+			w := 1.0
+			if g.Adjwgt != nil {
+				w = float64(g.Adjwgt[j])
+			}
+			total += w
+			volume[part[u]] += w
+			if part[g.Adjncy[j]] != part[u] {
+				cut[part[u]] += w
+			}
+		}
+	}
+
+	conductance := make([]float64, nparts)
+	for p := range conductance {
+		denom := volume[p]
+		if rest := total - volume[p]; rest < denom {
+			denom = rest
+		}
+		if denom > 0 {
+			conductance[p] = cut[p] / denom
+		}
+	}
+	return conductance
+}
+
+// PartitionBridges returns the bridges of partition p: the edges inside p
+// whose removal would split its induced subgraph into more connected pieces.
+// A partition held together by bridges is fragile, since losing a single
+// edge, or the link it models, fragments the subdomain. Each bridge is
+// returned once as {u, v} with u < v in original vertex ids, sorted. Parallel
+// edges are never bridges.
+func (g *Graph) PartitionBridges(part []int32, p int32) [][2]int32 {
+	var members []int32
+	for v, q := range part {
+		if q == p {
+			members = append(members, int32(v))
+		}
+	}
+	sub := g.Subgraph(members)
+	n := len(members)
+
+	// Iterative Tarjan low-link search; disc[v] == 0 marks v unvisited
+	disc := make([]int32, n)
+	low := make([]int32, n)
+	type frame struct {
+		v, parent   int32
+		next        int32 // Next adjacency position to scan
+		skippedBack bool  // Whether the edge back to parent was skipped
+	}
+	var bridges [][2]int32
+	var stack []frame
+	time := int32(0)
+	for root := 0; root < n; root++ {
+		if disc[root] != 0 {
+			continue
+		}
+		time++
+		disc[root], low[root] = time, time
+		stack = append(stack[:0], frame{v: int32(root), parent: -1, next: sub.Xadj[root]})
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.next < sub.Xadj[f.v+1] {
+				u := sub.Adjncy[f.next]
+				f.next++
+				switch {
+				case u == f.parent && !f.skippedBack:
+					f.skippedBack = true
+				case disc[u] == 0:
+					time++
+					disc[u], low[u] = time, time
+					stack = append(stack, frame{v: u, parent: f.v, next: sub.Xadj[u]})
+				case disc[u] < low[f.v]:
+					low[f.v] = disc[u]
+				}
+				continue
+			}
+
+			v, parent := f.v, f.parent
+			stack = stack[:len(stack)-1]
+			if parent < 0 {
+				continue
+			}
+			if low[v] < low[parent] {
+				low[parent] = low[v]
+			}
+			if low[v] > disc[parent] {
+				a, b := members[parent], members[v]
+				if a > b {
+					a, b = b, a
+				}
+				bridges = append(bridges, [2]int32{a, b})
+			}
+		}
+	}
+
+	sort.Slice(bridges, func(i, j int) bool {
+		if bridges[i][0] != bridges[j][0] {
+			return bridges[i][0] < bridges[j][0]
+		}
+		return bridges[i][1] < bridges[j][1]
+	})
+	return bridges
+}
+
+// CouplingMatrix returns the nparts by nparts matrix whose entry [a][b] is the
+// total weight of the edges cut between partitions a and b. The matrix is
+// symmetric with a zero diagonal, and the sum of its upper triangle is the
+// edge cut. Unit edge weights are used when g.Adjwgt is nil.
+func CouplingMatrix(g *Graph, part []int32, nparts int32) [][]int32 {
+	coupling := make([][]int32, nparts)
+	for p := range coupling {
+		coupling[p] = make([]int32, nparts)
+	}
+
+	g.EdgeIterator(func(u, v, weight int32) {
+		if a, b := part[u], part[v]; a != b {
+			coupling[a][b] += weight
+			coupling[b][a] += weight
+		}
+	})
+
+	return coupling
+}
+
+// MergeSmallPartitions merges every partition of fewer than minSize vertices
+// into a neighbor until all partitions reach minSize or a single partition is
+// left. The smallest partition is merged first, the lower id among equally
+// small ones. It goes into the partition it shares the heaviest cut with; on
+// equal cuts, including when it has no neighbor at all, the smaller partition
+// wins, and on equal sizes the lower id. The surviving partitions are renumbered
+// 0..newNparts-1 in their original order. part is not modified.
+func MergeSmallPartitions(g *Graph, part []int32, nparts int32, minSize int) (merged []int32, newNparts int32) {
+	coupling := CouplingMatrix(g, part, nparts)
+	sizes := make([]int, nparts)
+	for _, p := range part {
+		sizes[p]++
+	}
+	alive := make([]bool, nparts)
+	into := make([]int32, nparts) // Partition each partition was merged into
+	for p := range alive {
+		alive[p] = true
+		into[p] = int32(p)
+	}
+
+	for remaining := nparts; remaining > 1; remaining-- {
+		small := int32(-1)
+		for p := int32(0); p < nparts; p++ {
+			if alive[p] && sizes[p] < minSize && (small < 0 || sizes[p] < sizes[small]) {
+				small = p
+			}
+		}
+		if small < 0 {
+			break
+		}
+
+		target := int32(-1)
+		for q := int32(0); q < nparts; q++ {
+			if !alive[q] || q == small {
+				continue
+			}
+			if target < 0 || coupling[small][q] > coupling[small][target] ||
+				(coupling[small][q] == coupling[small][target] && sizes[q] < sizes[target]) {
+				target = q
+			}
+		}
+
+		alive[small] = false
+		into[small] = target
+		sizes[target] += sizes[small]
+		for q := int32(0); q < nparts; q++ {
+			coupling[target][q] += coupling[small][q]
+			coupling[q][target] += coupling[q][small]
+		}
+		coupling[target][target] = 0
+	}
+
+	label := make([]int32, nparts)
+	for p := int32(0); p < nparts; p++ {
+		if alive[p] {
+			label[p] = newNparts
+			newNparts++
+		}
+	}
+	merged = make([]int32, len(part))
+	for v, p := range part {
+		for !alive[p] {
+			p = into[p]
+		}
+		merged[v] = label[p]
+	}
+	return merged, newNparts
+}
+
+// CostModel describes a machine for EstimateParallelRuntime. Times are in
+// seconds, but any consistent unit works.
+type CostModel struct {
+	ComputePerWeight float64 // Time to process one unit of vertex weight
+	BytesPerCutEdge  float64 // Data exchanged per unit weight of cut edge
+	Bandwidth        float64 // Bytes per unit time; zero means unlimited
+	Latency          float64 // Time per message, one per neighboring partition
+}
+
+// EstimateParallelRuntime predicts the time of one compute-and-exchange step
+// of a parallel code distributed by part: the runtime of the slowest
+// partition, since every partition waits for it. A partition's time is its
+// vertex weight (unit weights when g.Vwgt is nil) times ComputePerWeight plus
+// the time to exchange its cut edges with its neighbors: a Latency per
+// neighboring partition and the weight of its cut edges times
+// BytesPerCutEdge over Bandwidth. Computation and communication are assumed
+// not to overlap.
+func EstimateParallelRuntime(g *Graph, part []int32, nparts int32, model CostModel) float64 {
+	coupling := CouplingMatrix(g, part, nparts)
+	weights := make([]float64, nparts)
+	for v, p := range part {
+		if g.Vwgt != nil {
+			weights[p] += float64(g.Vwgt[v])
+		} else {
+			weights[p]++
+		}
+	}
+
+	slowest := 0.0
+	for p := int32(0); p < nparts; p++ {
+		t := weights[p] * model.ComputePerWeight
+		for q := int32(0); q < nparts; q++ {
+			if coupling[p][q] == 0 {
+				continue
+			}
+			t += model.Latency
+			if model.Bandwidth > 0 {
+				t += float64(coupling[p][q]) * model.BytesPerCutEdge / model.Bandwidth
+			}
+		}
+		if t > slowest {
+			slowest = t
+		}
+	}
+	return slowest
+}
+
+// MaxCoupling returns the pair of partitions, a < b, joined by the heaviest
+// cut, and the weight of that cut. This is the communication hotspot that
+// tends to dominate the exchange time of a distributed solver. Ties go to the
+// lowest pair, and (-1, -1, 0) is returned when no edge is cut.
+func MaxCoupling(g *Graph, part []int32, nparts int32) (a, b int32, weight int32) {
+	coupling := CouplingMatrix(g, part, nparts)
+
+	a, b = -1, -1
+	for p := int32(0); p < nparts; p++ {
+		for q := p + 1; q < nparts; q++ {
+			if coupling[p][q] > weight {
+				a, b, weight = p, q, coupling[p][q]
+			}
+		}
+	}
+
+	return a, b, weight
+}
+
+// PartitionSimilarity returns the fraction of vertices on which partitions a
+// and b agree, from 0 to 1, once the partition ids of b are matched to those
+// of a. Partition ids are arbitrary, so two partitions that differ only by a
+// renumbering have similarity 1. Ids are matched greedily by largest overlap,
+// which may slightly underestimate the best possible agreement. a and b must
+// have the same length; otherwise the similarity is 0.
+func PartitionSimilarity(a, b []int32, nparts int32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	matched := append([]int32(nil), b...)
+	relabelToHint(matched, a, nparts)
+
+	agree := 0
+	for v := range a {
+		if matched[v] == a[v] {
+			agree++
+		}
+	}
+	return float64(agree) / float64(len(a))
+}
+
+// Rebalance moves vertices out of overweight partitions until the heaviest
+// partition weighs at most targetImbalance times the average, for callers
+// whose balance requirement is tighter than what METIS achieved. It repeatedly
+// takes the heaviest partition and makes the move of one of its vertices that
+// increases the edge cut least: a vertex may move to any partition it is
+// adjacent to or to the lightest partition, and the receiving partition must
+// stay lighter than the one the vertex leaves. part is not modified. It
+// returns the new partition, its edge cut and the imbalance achieved, which
+// exceeds targetImbalance when no further move helps, for instance because
+// vertex weights are too coarse. Vertices of zero weight are never moved, as
+// moving them cannot improve the balance.
+//
+// Candidate moves are kept in one priority queue per partition and only the
+// moved vertex and its neighbors are requeued after a move, so a move costs
+// about the squared degree of the moved vertex plus a logarithmic queue
+// update, not a pass over the graph.
+func Rebalance(g *Graph, part []int32, nparts int32, targetImbalance float64) (newPart []int32, newCut int32, imbalance float64) {
+	newPart = append([]int32(nil), part...)
+
+	total := int64(len(part))
+	if g.Vwgt != nil {
+		total = 0
+		for _, w := range g.Vwgt {
+			total += int64(w)
+		}
+	}
+	// Weights are integers, so comparing them with the floor of the limit is
+	// the same as comparing them with the limit itself
+	rebalance(g, g.Vwgt, newPart, nparts, int64(math.Floor(targetImbalance*float64(total)/float64(nparts))))
+
+	_, max, avg := CalculatePartitionBalance(newPart, g.Vwgt, nparts)
+	if avg > 0 {
+		imbalance = max / avg
+	}
+	return newPart, CalculateEdgeCut(g, newPart), imbalance
+}
+
+// rebalance carries out Rebalance on part in place, weighing vertices by vwgt,
+// or by one each when vwgt is nil, and stopping once no partition weighs more
+// than limit
+func rebalance(g *Graph, vwgt []int32, part []int32, nparts int32, limit int64) {
+	vertexWeight := func(v int32) int64 {
+		if vwgt != nil {
+			return int64(vwgt[v])
+		}
+		return 1
+	}
+	edgeWeight := func(j int32) int64 {
+		if g.Adjwgt != nil {
+			return int64(g.Adjwgt[j])
+		}
+		return 1
+	}
+
+	weights := make([]int64, nparts)
+	for v, p := range part {
+		weights[p] += vertexWeight(int32(v))
+	}
+
+	// connect fills conn with the edge weight from v to each partition, listing
+	// the partitions it reaches in touched; reset clears both
+	conn := make([]int64, nparts)
+	seen := make([]bool, nparts)
+	var touched []int32
+	connect := func(v int32) {
+		for j := g.Xadj[v]; j < g.Xadj[v+1]; j++ {
+			q := part[g.Adjncy[j]]
+			if !seen[q] {
+				seen[q] = true
+				touched = append(touched, q)
+			}
+			conn[q] += edgeWeight(j)
+		}
+	}
+	reset := func() {
+		for _, q := range touched {
+			conn[q], seen[q] = 0, false
+		}
+		touched = touched[:0]
+	}
+
+	// optimisticGain is the gain of the best move of v regardless of
+	// partition weights, an upper bound on the gain of any allowed move. A
+	// partition v is not adjacent to is reached at a gain of -internal.
+	optimisticGain := func(v int32) int64 {
+		connect(v)
+		best := int64(0)
+		for _, q := range touched {
+			if q != part[v] && conn[q] > best {
+				best = conn[q]
+			}
+		}
+		gain := best - conn[part[v]]
+		reset()
+		return gain
+	}
+
+	// bestMove returns the allowed move of v out of heaviest with the highest
+	// gain, preferring lower partition ids on ties; to is -1 if there is none.
+	// Any allowed partition v is not adjacent to has the same gain as the
+	// lightest, which is allowed whenever one of them is.
+	bestMove := func(v, heaviest, lightest int32) (gain int64, to int32) {
+		connect(v)
+		internal := conn[heaviest]
+		to = -1
+		consider := func(q int32) {
+			if q == heaviest || weights[q]+vertexWeight(v) >= weights[heaviest] {
+				return
+			}
+			if gq := conn[q] - internal; to < 0 || gq > gain || (gq == gain && q < to) {
+				gain, to = gq, q
+			}
+		}
+		for _, q := range touched {
+			consider(q)
+		}
+		consider(lightest)
+		reset()
+		return gain, to
+	}
+
+	// Entries whose stamp is behind stamp[v] are stale and skipped. Vertices
+	// without weight are never queued.
+	queues := make([]moveQueue, nparts)
+	stamp := make([]int32, len(part))
+	enqueue := func(v int32) {
+		if vertexWeight(v) <= 0 {
+			return
+		}
+		stamp[v]++
+		heap.Push(&queues[part[v]], moveEntry{v: v, gain: optimisticGain(v), stamp: stamp[v]})
+	}
+	for v := range part {
+		if vertexWeight(int32(v)) > 0 {
+			queues[part[v]] = append(queues[part[v]], moveEntry{v: int32(v), gain: optimisticGain(int32(v))})
+		}
+	}
+	for p := range queues {
+		heap.Init(&queues[p])
+	}
+
+	// Vertices whose allowed moves fell short of their optimistic gain; the
+	// next move changes the weights, so they get their optimistic gain back
+	var constrained []int32
+	for {
+		heaviest, lightest := int32(0), int32(0)
+		for p := int32(1); p < nparts; p++ {
+			if weights[p] > weights[heaviest] {
+				heaviest = p
+			}
+			if weights[p] < weights[lightest] {
+				lightest = p
+			}
+		}
+		if weights[heaviest] <= limit {
+			return
+		}
+
+		queue := &queues[heaviest]
+		moved := false
+		for queue.Len() > 0 && !moved {
+			e := heap.Pop(queue).(moveEntry)
+			if e.stamp != stamp[e.v] {
+				continue
+			}
+			gain, to := bestMove(e.v, heaviest, lightest)
+			if to < 0 || gain < e.gain {
+				// Another vertex may now do better; requeue v at its real gain
+				constrained = append(constrained, e.v)
+				if to >= 0 {
+					stamp[e.v]++
+					heap.Push(queue, moveEntry{v: e.v, gain: gain, stamp: stamp[e.v]})
+				}
+				continue
+			}
+
+			// Every queued gain is an upper bound, so no move beats this one
+			w := vertexWeight(e.v)
+			weights[heaviest] -= w
+			weights[to] += w
+			part[e.v] = to
+			enqueue(e.v)
+			for j := g.Xadj[e.v]; j < g.Xadj[e.v+1]; j++ {
+				enqueue(g.Adjncy[j])
+			}
+			moved = true
+		}
+		if !moved {
+			return
+		}
+		for _, v := range constrained {
+			enqueue(v)
+		}
+		constrained = constrained[:0]
+	}
+}
+
+// moveEntry is a queued move of vertex v out of its partition
+type moveEntry struct {
+	v     int32
+	gain  int64 // Reduction in edge cut, or an upper bound on it
+	stamp int32
+}
+
+// moveQueue is a max-heap of moves by gain, then by lowest vertex id
+type moveQueue []moveEntry
+
+func (h moveQueue) Len() int { return len(h) }
+func (h moveQueue) Less(i, j int) bool {
+	return h[i].gain > h[j].gain || (h[i].gain == h[j].gain && h[i].v < h[j].v)
+}
+func (h moveQueue) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *moveQueue) Push(x interface{}) { *h = append(*h, x.(moveEntry)) }
+func (h *moveQueue) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// MigrationVolume returns the amount of data that moves when a graph is
+// repartitioned from oldPart to newPart: the sum of vsize over the vertices
+// whose partition changed, or their count when vsize is nil. Comparing it with
+// the imbalance removed helps decide whether repartitioning pays off. Since
+// partition ids are arbitrary, newPart should be numbered to match oldPart, as
+// PartGraphKwayHinted does. Both slices must have one entry per vertex.
+func MigrationVolume(oldPart, newPart []int32, vsize []int32) int64 {
+	volume := int64(0)
+	for i := range oldPart {
+		if oldPart[i] == newPart[i] {
+			continue
+		}
+		if vsize != nil {
+			volume += int64(vsize[i])
+		} else {
+			volume++
+		}
+	}
+	return volume
+}
+
+// AssignPartitionColors colors the quotient graph so that adjacent partitions
+// get different colors whenever ncolors allows it, which keeps neighboring
+// partitions distinguishable when drawing them. Partitions are colored
+// greedily in order of decreasing degree; when every color is already taken by
+// a neighbor, the color shared with the lightest total coupling is reused.
+// It returns a color index in [0, ncolors) for each partition.
+func AssignPartitionColors(quotient *Graph, nparts int32, ncolors int) []int {
+	colors := make([]int, nparts)
+	for p := range colors {
+		colors[p] = -1
+	}
+	if ncolors < 1 {
+		return colors
+	}
+
+	order := make([]int, nparts)
+	for p := range order {
+		order[p] = p
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return quotient.Degree(order[a]) > quotient.Degree(order[b])
+	})
+
+	// conflict[c] is the coupling weight to neighbors already colored c
+	conflict := make([]int64, ncolors)
+	for _, p := range order {
+		for c := range conflict {
+			conflict[c] = 0
+		}
+		for j := quotient.Xadj[p]; j < quotient.Xadj[p+1]; j++ {
+			c := colors[quotient.Adjncy[j]]
+			if c < 0 {
+				continue
+			}
+			// Weight by coupling, plus one so unweighted edges still count
+			w := int64(1)
+			if quotient.Adjwgt != nil {
+				w += int64(quotient.Adjwgt[j])
+			}
+			conflict[c] += w
+		}
+
+		best := 0
+		for c := 1; c < ncolors; c++ {
+			if conflict[c] < conflict[best] {
+				best = c
+			}
+		}
+		colors[p] = best
+	}
+
+	return colors
+}
+
+// CoreTopology describes the machine MapPartitionsToCores maps onto: Sockets
+// NUMA sockets of CoresPerSocket cores each. Cores are numbered socket by
+// socket, so core c sits on socket c / CoresPerSocket, which matches the
+// numbering of lscpu and hwloc logical indices on most systems.
+type CoreTopology struct {
+	Sockets        int
+	CoresPerSocket int
+}
+
+// MapPartitionsToCores assigns every partition, a vertex of quotient (see
+// QuotientGraph), to a core so that strongly coupled partitions share a
+// socket. Sockets are filled one at a time by graph growing: each starts from
+// the heaviest unplaced partition by quotient.Vwgt and repeatedly adds the
+// unplaced partition with the largest coupling to those already on the
+// socket, the heavier one on ties and then the lower id. Partitions weigh the
+// same when quotient.Vwgt is nil, and unit coupling is used when
+// quotient.Adjwgt is nil. When there are more partitions than
+// cores, the sockets are filled evenly and cores are shared. It returns the
+// core id of each partition, or nil for a topology without cores.
+func MapPartitionsToCores(quotient *Graph, topology CoreTopology) []int {
+	if topology.Sockets < 1 || topology.CoresPerSocket < 1 {
+		return nil
+	}
+	nparts := quotient.NumVertices()
+	capacity := topology.CoresPerSocket
+	if nparts > topology.Sockets*capacity {
+		capacity = (nparts + topology.Sockets - 1) / topology.Sockets
+	}
+
+	core := make([]int, nparts)
+	weight := make([]int64, nparts)
+	for p := range core {
+		core[p] = -1
+		if quotient.Vwgt != nil {
+			weight[p] = int64(quotient.Vwgt[p])
+		}
+	}
+	// gain[p] is the coupling of unplaced partition p to the current socket
+	gain := make([]int64, nparts)
+	placed := 0
+	for socket := 0; placed < nparts; socket++ {
+		for p := range gain {
+			gain[p] = 0
+		}
+		for slot := 0; slot < capacity && placed < nparts; slot++ {
+			best := -1
+			for p := 0; p < nparts; p++ {
+				if core[p] >= 0 {
+					continue
+				}
+				if best < 0 || gain[p] > gain[best] ||
+					gain[p] == gain[best] && weight[p] > weight[best] {
+					best = p
+				}
+			}
+
+			core[best] = socket*topology.CoresPerSocket + slot%topology.CoresPerSocket
+			placed++
+			for j := quotient.Xadj[best]; j < quotient.Xadj[best+1]; j++ {
+				if quotient.Adjwgt != nil {
+					gain[quotient.Adjncy[j]] += int64(quotient.Adjwgt[j])
+				} else {
+					gain[quotient.Adjncy[j]]++
+				}
+			}
+		}
+	}
+
+	return core
+}
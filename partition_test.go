@@ -0,0 +1,535 @@
+package metis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunicationPlan(t *testing.T) {
+	// 3x2 grid split into three columns
+	//   0 - 1 - 2
+	//   |   |   |
+	//   3 - 4 - 5
+	xadj, adjncy := createGridGraph(3, 2)
+	g := NewGraph(xadj, adjncy)
+	part := []int32{0, 1, 2, 0, 1, 2}
+
+	plan := CommunicationPlan(g, part, 3)
+	assert.Equal(t, map[int32]map[int32][]int32{
+		0: {1: {0, 3}},
+		1: {0: {1, 4}, 2: {1, 4}},
+		2: {1: {2, 5}},
+	}, plan)
+
+	// A single partition has nothing to exchange
+	assert.Empty(t, CommunicationPlan(g, make([]int32, 6), 1))
+}
+
+func TestVerifyBalance(t *testing.T) {
+	// 100 unit vertices in 2 parts: 51/49 is 2% over average
+	part := make([]int32, 100)
+	for i := 51; i < 100; i++ {
+		part[i] = 1
+	}
+	assert.NoError(t, VerifyBalance(part, nil, 2, 30))
+	assert.NoError(t, VerifyBalance(part, nil, 2, 20))
+
+	err := VerifyBalance(part, nil, 2, 10)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 0")
+
+	// Vertex weights shift the imbalance onto partition 1
+	vwgt := make([]int32, 100)
+	for i := range vwgt {
+		vwgt[i] = 1
+	}
+	vwgt[99] = 10
+	err = VerifyBalance(part, vwgt, 2, 30)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 1")
+
+	// Out of range assignments are reported
+	part[3] = 2
+	assert.Error(t, VerifyBalance(part, nil, 2, 30))
+}
+
+func TestHyperedgeCut(t *testing.T) {
+	// Six vertices, four nets:
+	//   net 0 = {0, 1, 2}     inside partition 0
+	//   net 1 = {2, 3}        spans partitions 0 and 1
+	//   net 2 = {1, 3, 5}     spans partitions 0, 1 and 2
+	//   net 3 = {4, 5}        inside partition 2
+	hptr := []int32{0, 3, 5, 8, 10}
+	hind := []int32{0, 1, 2, 2, 3, 1, 3, 5, 4, 5}
+	part := []int32{0, 0, 0, 1, 2, 2}
+
+	cutNets, km1 := HyperedgeCut(4, hptr, hind, part)
+	assert.Equal(t, int32(2), cutNets)
+	assert.Equal(t, int32(3), km1)
+
+	// Everything in one partition cuts nothing
+	cutNets, km1 = HyperedgeCut(4, hptr, hind, make([]int32, 6))
+	assert.Equal(t, int32(0), cutNets)
+	assert.Equal(t, int32(0), km1)
+}
+
+func TestQuotientGraph(t *testing.T) {
+	// 4x4 grid split into 2x2 quadrants
+	xadj, adjncy := createGridGraph(4, 4)
+	g := NewGraph(xadj, adjncy)
+	part := []int32{
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+		2, 2, 3, 3,
+		2, 2, 3, 3,
+	}
+
+	q := QuotientGraph(g, part, 4)
+	assert.Equal(t, 4, q.NumVertices())
+	assert.Equal(t, []int32{4, 4, 4, 4}, q.Vwgt)
+	// Quadrants form a 4-cycle, each side cut by two edges
+	assert.Equal(t, 4, q.NumEdges())
+	for _, w := range q.Adjwgt {
+		assert.Equal(t, int32(2), w)
+	}
+}
+
+func TestPartitionMembers(t *testing.T) {
+	part := []int32{2, 0, 2, 1, 0, 2, -1}
+	members := PartitionMembers(part, 4)
+	assert.Equal(t, [][]int32{{1, 4}, {3}, {0, 2, 5}, {}}, members)
+
+	// The lists are independent despite sharing storage
+	members[0] = append(members[0], 9)
+	assert.Equal(t, []int32{3}, members[1])
+}
+
+func TestMetrics(t *testing.T) {
+	// 4x4 grid split into 2x2 quadrants
+	g := NewGraph(createGridGraph(4, 4))
+	part := []int32{
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+		2, 2, 3, 3,
+		2, 2, 3, 3,
+	}
+
+	m := Metrics(g, part, 4)
+	assert.Equal(t, int32(8), m.EdgeCut)
+	// The four center vertices each see two other quadrants, the other eight
+	// boundary vertices one
+	assert.Equal(t, int32(16), m.CommVolume)
+	assert.Equal(t, int32(12), m.BoundaryVertices)
+	assert.Equal(t, CalculateEdgeCut(g, part), m.EdgeCut)
+
+	assert.Equal(t, PartitionMetrics{}, Metrics(g, make([]int32, 16), 1))
+}
+
+func TestCouplingMatrix(t *testing.T) {
+	// 4x4 grid split into 2x2 quadrants, with heavy edges between 1 and 3
+	g := NewGraph(createGridGraph(4, 4))
+	g.Adjwgt = make([]int32, len(g.Adjncy))
+	for i := range g.Adjwgt {
+		g.Adjwgt[i] = 1
+	}
+	part := []int32{
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+		2, 2, 3, 3,
+		2, 2, 3, 3,
+	}
+	for _, e := range [][2]int32{{6, 10}, {7, 11}} {
+		for j := g.Xadj[e[0]]; j < g.Xadj[e[0]+1]; j++ {
+			if g.Adjncy[j] == e[1] {
+				g.Adjwgt[j] = 5
+			}
+		}
+		for j := g.Xadj[e[1]]; j < g.Xadj[e[1]+1]; j++ {
+			if g.Adjncy[j] == e[0] {
+				g.Adjwgt[j] = 5
+			}
+		}
+	}
+
+	assert.Equal(t, [][]int32{
+		{0, 2, 2, 0},
+		{2, 0, 0, 10},
+		{2, 0, 0, 2},
+		{0, 10, 2, 0},
+	}, CouplingMatrix(g, part, 4))
+
+	a, b, w := MaxCoupling(g, part, 4)
+	assert.Equal(t, []int32{1, 3, 10}, []int32{a, b, w})
+
+	// Ties go to the lowest pair; no cut yields no pair
+	g.Adjwgt = nil
+	a, b, w = MaxCoupling(g, part, 4)
+	assert.Equal(t, []int32{0, 1, 2}, []int32{a, b, w})
+	a, b, w = MaxCoupling(g, make([]int32, 16), 1)
+	assert.Equal(t, []int32{-1, -1, 0}, []int32{a, b, w})
+}
+
+func TestMigrationVolume(t *testing.T) {
+	oldPart := []int32{0, 0, 1, 1, 2}
+	newPart := []int32{0, 1, 1, 2, 2}
+	assert.Equal(t, int64(2), MigrationVolume(oldPart, newPart, nil))
+	assert.Equal(t, int64(30), MigrationVolume(oldPart, newPart, []int32{1, 10, 100, 20, 1000}))
+	assert.Zero(t, MigrationVolume(oldPart, oldPart, nil))
+
+	// Paired with a hinted repartitioning, the unit volume is the number of
+	// vertices behind the reported divergence
+	g := NewGraph(createGridGraph(6, 6))
+	hint := make([]int32, 36)
+	for v := range hint {
+		hint[v] = int32(v % 6 / 2)
+	}
+	part, _, divergence, err := PartGraphKwayHinted(g, 3, hint, 1, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, divergence*36, float64(MigrationVolume(hint, part, nil)), 1e-9)
+}
+
+func TestAssignPartitionColors(t *testing.T) {
+	xadj, adjncy := createGridGraph(4, 4)
+	g := NewGraph(xadj, adjncy)
+	part := []int32{
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+		2, 2, 3, 3,
+		2, 2, 3, 3,
+	}
+	q := QuotientGraph(g, part, 4)
+
+	// A 4-cycle is 2-colorable
+	colors := AssignPartitionColors(q, 4, 2)
+	assertProperColoring(t, q, colors)
+
+	// Stripes with 3 partitions form a path; 2 colors are enough
+	part = []int32{0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2}
+	q = QuotientGraph(g, part, 3)
+	colors = AssignPartitionColors(q, 3, 2)
+	assertProperColoring(t, q, colors)
+
+	// A triangle cannot be 2-colored, but colors stay in range
+	tri := NewGraph([]int32{0, 2, 4, 6}, []int32{1, 2, 0, 2, 0, 1})
+	colors = AssignPartitionColors(tri, 3, 2)
+	for _, c := range colors {
+		assert.True(t, c == 0 || c == 1)
+	}
+	colors = AssignPartitionColors(tri, 3, 3)
+	assertProperColoring(t, tri, colors)
+}
+
+func assertProperColoring(t *testing.T, q *Graph, colors []int) {
+	t.Helper()
+	for p := 0; p < q.NumVertices(); p++ {
+		for _, n := range q.Neighbors(p) {
+			assert.NotEqual(t, colors[p], colors[n], "partitions %d and %d share a color", p, n)
+		}
+	}
+}
+
+func TestVerifyPartition(t *testing.T) {
+	xadj, adjncy := createGridGraph(4, 4)
+	part := []int32{
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+		2, 2, 3, 3,
+		2, 2, 3, 3,
+	}
+	assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 4, 8, part))
+
+	err := VerifyPartition(xadj, adjncy, nil, nil, 4, 7, part)
+	assert.ErrorContains(t, err, "edge cut mismatch")
+
+	err = VerifyPartition(xadj, adjncy, nil, nil, 5, 8, part)
+	assert.ErrorContains(t, err, "highest partition")
+
+	err = VerifyPartition(xadj, adjncy, nil, nil, 3, 8, part)
+	assert.ErrorContains(t, err, "outside")
+
+	// Heavy vertex weights unbalance partition 0
+	vwgt := make([]int32, 16)
+	for i := range vwgt {
+		vwgt[i] = 1
+	}
+	vwgt[0] = 10
+	err = VerifyPartition(xadj, adjncy, vwgt, nil, 4, 8, part)
+	assert.ErrorContains(t, err, "imbalance")
+
+	t.Run("MatchesMETIS", func(t *testing.T) {
+		opts := make([]int32, NoOptions)
+		SetDefaultOptions(opts)
+		xadj, adjncy := createGridGraph(10, 10)
+		part, objval, err := PartGraphKway(xadj, adjncy, 4, opts)
+		require.NoError(t, err)
+		assert.NoError(t, VerifyPartition(xadj, adjncy, nil, nil, 4, objval, part))
+	})
+}
+
+func TestMapPartitionsToCores(t *testing.T) {
+	// Two tightly coupled pairs {0,2} and {1,3}, weakly linked 0-1 and 2-3
+	q := &Graph{
+		Xadj:   []int32{0, 2, 4, 6, 8},
+		Adjncy: []int32{1, 2, 0, 3, 0, 3, 1, 2},
+		Adjwgt: []int32{1, 10, 1, 10, 10, 1, 10, 1},
+	}
+
+	cores := MapPartitionsToCores(q, CoreTopology{Sockets: 2, CoresPerSocket: 2})
+	require.Len(t, cores, 4)
+	socket := func(p int) int { return cores[p] / 2 }
+	assert.Equal(t, socket(0), socket(2))
+	assert.Equal(t, socket(1), socket(3))
+	assert.NotEqual(t, socket(0), socket(1))
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, cores)
+
+	// The first socket starts from the heaviest partition
+	q.Vwgt = []int32{1, 1, 1, 5}
+	cores = MapPartitionsToCores(q, CoreTopology{Sockets: 2, CoresPerSocket: 2})
+	assert.Equal(t, 0, cores[3])
+	assert.Equal(t, 1, cores[1])
+	q.Vwgt = nil
+
+	// Oversubscribed: eight partitions on four cores share them evenly
+	xadj, adjncy := createGridGraph(4, 2)
+	cores = MapPartitionsToCores(NewGraph(xadj, adjncy), CoreTopology{Sockets: 2, CoresPerSocket: 2})
+	require.Len(t, cores, 8)
+	perCore := make([]int, 4)
+	for _, c := range cores {
+		perCore[c]++
+	}
+	assert.Equal(t, []int{2, 2, 2, 2}, perCore)
+
+	assert.Nil(t, MapPartitionsToCores(q, CoreTopology{}))
+}
+
+func TestPartitionDensities(t *testing.T) {
+	// A 4-clique {0,1,2,3} and a path 4-5-6, joined by the edge 3-4
+	g := &Graph{
+		Xadj:   []int32{0, 3, 6, 9, 13, 15, 17, 18},
+		Adjncy: []int32{1, 2, 3, 0, 2, 3, 0, 1, 3, 0, 1, 2, 4, 3, 5, 4, 6, 5},
+		Adjwgt: []int32{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 9, 9, 5, 5, 5, 5},
+	}
+	part := []int32{0, 0, 0, 0, 1, 1, 1}
+
+	d := g.PartitionDensities(part, 3)
+	require.Len(t, d, 3)
+	assert.Equal(t, 1.0, d[0])
+	assert.InDelta(t, 2.0/3.0, d[1], 1e-12)
+	assert.Zero(t, d[2])
+
+	// Halves of a clique are complete; a lone vertex has density 0
+	d = g.PartitionDensities([]int32{0, 0, 1, 1, 2, 2, 2}, 3)
+	assert.Equal(t, []float64{1, 1, 2.0 / 3.0}, d)
+	d = g.PartitionDensities([]int32{0, 0, 0, 1, 2, 2, 2}, 3)
+	assert.Zero(t, d[1])
+}
+
+func TestPartitionBridges(t *testing.T) {
+	// Partition 0 is the triangle 0-1-2 with a tail 2-3-4 and a doubled edge
+	// 4-5; partition 1 is the cycle 6-7-8, attached to vertex 1 by 1-6
+	edges := [][2]int32{
+		{0, 1}, {1, 2}, {0, 2}, {2, 3}, {3, 4}, {4, 5}, {4, 5},
+		{6, 7}, {7, 8}, {6, 8}, {1, 6},
+	}
+	adj := make([][]int32, 9)
+	for _, e := range edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0])
+	}
+	g := &Graph{Xadj: []int32{0}}
+	for _, nbrs := range adj {
+		g.Adjncy = append(g.Adjncy, nbrs...)
+		g.Xadj = append(g.Xadj, int32(len(g.Adjncy)))
+	}
+	part := []int32{0, 0, 0, 0, 0, 0, 1, 1, 1}
+
+	assert.Equal(t, [][2]int32{{2, 3}, {3, 4}}, g.PartitionBridges(part, 0))
+	assert.Empty(t, g.PartitionBridges(part, 1))
+	assert.Empty(t, g.PartitionBridges(part, 2))
+
+	// A path is all bridges, including across separate pieces of a partition
+	xadj, adjncy := createGridGraph(5, 1)
+	path := NewGraph(xadj, adjncy)
+	assert.Equal(t, [][2]int32{{0, 1}, {3, 4}}, path.PartitionBridges([]int32{0, 0, 1, 0, 0}, 0))
+}
+
+func TestPartitionSimilarity(t *testing.T) {
+	a := []int32{0, 0, 1, 1, 2, 2}
+
+	// A renumbering is a perfect match
+	assert.Equal(t, 1.0, PartitionSimilarity(a, []int32{2, 2, 0, 0, 1, 1}, 3))
+	assert.InDelta(t, 5.0/6, PartitionSimilarity(a, []int32{1, 1, 0, 0, 2, 0}, 3), 1e-12)
+	assert.Zero(t, PartitionSimilarity(a, a[:3], 3))
+}
+
+func TestModularity(t *testing.T) {
+	// Two triangles joined by the single edge 2-3
+	g := NewGraph(
+		[]int32{0, 2, 4, 7, 10, 12, 14},
+		[]int32{1, 2, 0, 2, 0, 1, 3, 2, 4, 5, 3, 5, 3, 4},
+	)
+
+	// in = 6 of 2m = 14 per triangle, tot = 7
+	communities := []int32{0, 0, 0, 1, 1, 1}
+	assert.InDelta(t, 2*(6.0/14-0.25), g.Modularity(communities), 1e-12)
+	assert.InDelta(t, 5.0/14, g.Modularity(communities), 1e-12)
+
+	// The natural split beats a mixed one, and a single community scores 0
+	assert.Greater(t, g.Modularity(communities), g.Modularity([]int32{0, 1, 0, 1, 0, 1}))
+	assert.InDelta(t, 0, g.Modularity(make([]int32, 6)), 1e-12)
+
+	// Heavier bridging weight lowers the modularity of the split
+	g.Adjwgt = []int32{1, 1, 1, 1, 1, 1, 5, 5, 1, 1, 1, 1, 1, 1}
+	assert.Less(t, g.Modularity(communities), 5.0/14)
+
+	assert.Zero(t, NewGraph([]int32{0, 0, 0}, nil).Modularity([]int32{0, 1}))
+}
+
+func TestConductance(t *testing.T) {
+	// Two triangles joined by the single edge 2-3
+	g := NewGraph(
+		[]int32{0, 2, 4, 7, 10, 12, 14},
+		[]int32{1, 2, 0, 2, 0, 1, 3, 2, 4, 5, 3, 5, 3, 4},
+	)
+
+	// One cut edge against a volume of 7 on either side
+	assert.InDeltaSlice(t, []float64{1.0 / 7, 1.0 / 7}, g.Conductance([]int32{0, 0, 0, 1, 1, 1}, 2), 1e-12)
+
+	// Splitting a triangle cuts far more of its volume
+	c := g.Conductance([]int32{0, 0, 1, 1, 1, 1}, 2)
+	assert.InDelta(t, 2.0/4, c[0], 1e-12)
+	assert.Equal(t, c[0], c[1])
+
+	// An empty partition has conductance 0
+	assert.Equal(t, []float64{0, 0, 0}, g.Conductance(make([]int32, 6), 3))
+
+	g.Adjwgt = []int32{1, 1, 1, 1, 1, 1, 3, 3, 1, 1, 1, 1, 1, 1}
+	assert.InDeltaSlice(t, []float64{3.0 / 9, 3.0 / 9}, g.Conductance([]int32{0, 0, 0, 1, 1, 1}, 2), 1e-12)
+}
+
+func TestRebalance(t *testing.T) {
+	xadj, adjncy := createGridGraph(8, 8)
+	g := NewGraph(xadj, adjncy)
+
+	// Rows 0-5 in partition 0 and rows 6-7 in partition 1
+	part := make([]int32, 64)
+	for v := 48; v < 64; v++ {
+		part[v] = 1
+	}
+	orig := append([]int32(nil), part...)
+
+	newPart, cut, imbalance := Rebalance(g, part, 2, 1.05)
+	assert.Equal(t, orig, part)
+	assert.LessOrEqual(t, imbalance, 1.05)
+	assert.Equal(t, CalculateEdgeCut(g, newPart), cut)
+	_, max, avg := CalculatePartitionBalance(newPart, nil, 2)
+	assert.InDelta(t, max/avg, imbalance, 1e-12)
+	// Moving vertices one boundary row at a time keeps the cut small
+	assert.LessOrEqual(t, cut, int32(16))
+
+	// An already balanced partition is left alone
+	same, sameCut, _ := Rebalance(g, newPart, 2, 1.05)
+	assert.Equal(t, newPart, same)
+	assert.Equal(t, cut, sameCut)
+
+	// A single heavy vertex cannot be split
+	g.Vwgt = make([]int32, 64)
+	for v := range g.Vwgt {
+		g.Vwgt[v] = 1
+	}
+	g.Vwgt[0] = 100
+	_, _, imbalance = Rebalance(g, part, 2, 1.05)
+	assert.Greater(t, imbalance, 1.05)
+
+	// Vertices without weight stay put: row 5 borders partition 1 and would
+	// otherwise be the cheapest to move
+	for v := range g.Vwgt {
+		g.Vwgt[v] = 1
+	}
+	for v := 40; v < 48; v++ {
+		g.Vwgt[v] = 0
+	}
+	newPart, _, imbalance = Rebalance(g, part, 2, 1.05)
+	assert.LessOrEqual(t, imbalance, 1.05)
+	for v := 40; v < 48; v++ {
+		assert.Equal(t, int32(0), newPart[v], "vertex %d", v)
+	}
+
+	// Thousands of moves on a larger grid stay fast, as each move only
+	// requeues the neighbors of the moved vertex
+	big := GenerateGrid2D(150, 150, false)
+	part = make([]int32, big.NumVertices())
+	for v := 150 * 120; v < len(part); v++ {
+		part[v] = 1
+	}
+	newPart, cut, imbalance = Rebalance(big, part, 2, 1.0)
+	assert.Equal(t, 1.0, imbalance)
+	assert.Equal(t, int32(150), cut)
+}
+
+func TestMergeSmallPartitions(t *testing.T) {
+	// A path of 12 vertices
+	xadj := []int32{0}
+	var adjncy []int32
+	for v := int32(0); v < 12; v++ {
+		if v > 0 {
+			adjncy = append(adjncy, v-1)
+		}
+		if v < 11 {
+			adjncy = append(adjncy, v+1)
+		}
+		xadj = append(xadj, int32(len(adjncy)))
+	}
+	g := NewGraph(xadj, adjncy)
+
+	part := []int32{0, 0, 0, 0, 1, 2, 2, 2, 2, 3, 3, 3}
+	merged, nparts := MergeSmallPartitions(g, part, 4, 3)
+	assert.Equal(t, int32(3), nparts)
+	assert.Equal(t, []int32{0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2}, merged)
+	assert.Equal(t, int32(1), part[4])
+
+	// Empty partitions disappear
+	merged, nparts = MergeSmallPartitions(g, part, 6, 1)
+	assert.Equal(t, int32(4), nparts)
+	assert.Equal(t, part, merged)
+
+	// Everything collapses into one partition at most
+	merged, nparts = MergeSmallPartitions(g, part, 4, 100)
+	assert.Equal(t, int32(1), nparts)
+	assert.Equal(t, make([]int32, 12), merged)
+}
+
+func TestEstimateParallelRuntime(t *testing.T) {
+	// Path 0-1-2-3 split as {0}, {1, 2}, {3}
+	g := NewGraph([]int32{0, 1, 3, 5, 6}, []int32{1, 0, 2, 1, 3, 2})
+	part := []int32{0, 1, 1, 2}
+
+	// Computation only: the middle partition has twice the work
+	assert.InDelta(t, 2.0, EstimateParallelRuntime(g, part, 3, CostModel{ComputePerWeight: 1}), 1e-12)
+
+	// The middle partition also talks to two neighbors: 2 + 2*0.5 + 2*8/16
+	model := CostModel{ComputePerWeight: 1, BytesPerCutEdge: 8, Bandwidth: 16, Latency: 0.5}
+	assert.InDelta(t, 4.0, EstimateParallelRuntime(g, part, 3, model), 1e-12)
+
+	// Heavy vertex weights move the bottleneck to partition 3
+	g.Vwgt = []int32{1, 1, 1, 10}
+	assert.InDelta(t, 11.0, EstimateParallelRuntime(g, part, 3, model), 1e-12)
+}
+
+func TestValidatePartition(t *testing.T) {
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+
+	assert.NoError(t, ValidatePartition([]int32{0, 1, 1, 0}, 2))
+	assert.Empty(t, rec.messages)
+
+	// Empty partitions are allowed but reported
+	assert.NoError(t, ValidatePartition([]int32{0, 2, 2, 0}, 4))
+	assert.Equal(t, []string{"warn: ValidatePartition: 2 of 4 partitions are empty: [1 3]"}, rec.messages)
+
+	assert.EqualError(t, ValidatePartition([]int32{0, 1, 2, -1}, 2), "vertex 2 assigned to partition 2, outside [0, 2)")
+	assert.Error(t, ValidatePartition([]int32{0}, 0))
+}
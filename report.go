@@ -0,0 +1,231 @@
+package metis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultReportDOTLimit is a sensible dotLimit for PartitionAndReport:
+// drawings of graphs with more vertices are rarely readable
+const DefaultReportDOTLimit = 1000
+
+// PartitionAndReport partitions g into nparts with PartitionGraph and writes
+// the result to outDir, which is created if needed:
+//
+//   - partition.txt: the partition of every vertex, one per line, as written
+//     by WritePartitioning
+//   - partition.stats: "key value" lines, see WritePartitionStats
+//   - partition.dot: the graph colored by partition, as written by WriteDOT,
+//     only when g has at most dotLimit vertices; zero disables it
+func PartitionAndReport(g *Graph, nparts int32, outDir string, options []int32, dotLimit int) error {
+	part, objval, err := PartitionGraph(g, nparts, options)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	if err := writeReportFile(filepath.Join(outDir, "partition.txt"), func(w io.Writer) error {
+		return WritePartitioning(w, part)
+	}); err != nil {
+		return err
+	}
+	if err := writeReportFile(filepath.Join(outDir, "partition.stats"), func(w io.Writer) error {
+		return WritePartitionStats(w, g, part, nparts, objval)
+	}); err != nil {
+		return err
+	}
+	if g.NumVertices() > dotLimit {
+		debugf("PartitionAndReport: %d vertices exceed the DOT limit of %d, skipping partition.dot", g.NumVertices(), dotLimit)
+		return nil
+	}
+	return writeReportFile(filepath.Join(outDir, "partition.dot"), func(w io.Writer) error {
+		return WriteDOT(w, g, part)
+	})
+}
+
+// writeReportFile creates path and fills it through write, buffering the
+// output
+func writeReportFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(f)
+	if err := write(out); err != nil {
+		f.Close()
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WritePartitionStats writes the quality of a partition as one "key value"
+// pair per line, in this order:
+//
+//	vertices <n>
+//	edges <n>
+//	nparts <n>
+//	objval <n>
+//	edgecut <n>
+//	commvolume <n>
+//	boundary <n>
+//	imbalance <max/avg partition weight, 3 decimals>
+//	part <p> <vertices> <weight>    (one line per partition)
+//
+// Keys are never renamed or reordered; new keys are only ever appended ahead
+// of the part lines.
+func WritePartitionStats(w io.Writer, g *Graph, part []int32, nparts, objval int32) error {
+	m := Metrics(g, part, nparts)
+	_, max, avg := CalculatePartitionBalance(part, g.Vwgt, nparts)
+	imbalance := 0.0
+	if avg > 0 {
+		imbalance = max / avg
+	}
+
+	counts := make([]int, nparts)
+	weights := make([]int64, nparts)
+	for v, p := range part {
+		counts[p]++
+		if g.Vwgt != nil {
+			weights[p] += int64(g.Vwgt[v])
+		} else {
+			weights[p]++
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "vertices %d\nedges %d\nnparts %d\nobjval %d\nedgecut %d\ncommvolume %d\nboundary %d\nimbalance %.3f\n",
+		g.NumVertices(), g.NumEdges(), nparts, objval, m.EdgeCut, m.CommVolume, m.BoundaryVertices, imbalance); err != nil {
+		return err
+	}
+	for p := int32(0); p < nparts; p++ {
+		if _, err := fmt.Fprintf(w, "part %d %d %d\n", p, counts[p], weights[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePartitionCSV writes part as CSV for spreadsheets and data frames: a
+// header row, then one "vertex_id,partition" row per vertex. With
+// includeDegree, the vertex weight (1 when g.Vwgt is nil) and degree are added
+// as the columns "weight" and "degree". Rows are streamed through a buffer, so
+// memory use does not grow with the graph.
+func WritePartitionCSV(w io.Writer, g *Graph, part []int32, includeDegree bool) error {
+	if len(part) != g.NumVertices() {
+		return fmt.Errorf("part has %d entries but the graph has %d vertices", len(part), g.NumVertices())
+	}
+
+	out := bufio.NewWriter(w)
+	header := "vertex_id,partition\n"
+	if includeDegree {
+		header = "vertex_id,partition,weight,degree\n"
+	}
+	if _, err := out.WriteString(header); err != nil {
+		return err
+	}
+
+	var buf []byte
+	for v, p := range part {
+		buf = strconv.AppendInt(buf[:0], int64(v), 10)
+		buf = append(buf, ',')
+		buf = strconv.AppendInt(buf, int64(p), 10)
+		if includeDegree {
+			weight := int64(1)
+			if g.Vwgt != nil {
+				weight = int64(g.Vwgt[v])
+			}
+			buf = append(buf, ',')
+			buf = strconv.AppendInt(buf, weight, 10)
+			buf = append(buf, ',')
+			buf = strconv.AppendInt(buf, int64(g.Degree(v)), 10)
+		}
+		buf = append(buf, '\n')
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// partitionPalette holds the colors the visualization exporters give to
+// partitions, cycling through them by partition id. They are the qualitative
+// ColorBrewer Set3 scheme, distinct on screen and in print.
+var partitionPalette = [][3]uint8{
+	{0x8d, 0xd3, 0xc7}, {0xff, 0xff, 0xb3}, {0xbe, 0xba, 0xda}, {0xfb, 0x80, 0x72},
+	{0x80, 0xb1, 0xd3}, {0xfd, 0xb4, 0x62}, {0xb3, 0xde, 0x69}, {0xfc, 0xcd, 0xe5},
+	{0xd9, 0xd9, 0xd9}, {0xbc, 0x80, 0xbd}, {0xcc, 0xeb, 0xc5}, {0xff, 0xed, 0x6f},
+}
+
+// partitionColor returns the palette color of partition p; negative ids, such
+// as unassigned vertices, are drawn black
+func partitionColor(p int32) [3]uint8 {
+	if p < 0 {
+		return [3]uint8{}
+	}
+	return partitionPalette[int(p)%len(partitionPalette)]
+}
+
+// WriteDOT writes g as an undirected Graphviz graph. Vertices are named by
+// their index; with a non-nil part each vertex is labeled and filled by its
+// partition, and cut edges are drawn dashed. Edge weights, when present,
+// become edge labels.
+func WriteDOT(w io.Writer, g *Graph, part []int32) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "graph G {")
+	fmt.Fprintln(out, "  node [style=filled];")
+	for v := 0; v < g.NumVertices(); v++ {
+		if part != nil {
+			c := partitionColor(part[v])
+			fmt.Fprintf(out, "  %d [label=\"%d:%d\", fillcolor=\"#%02x%02x%02x\"];\n",
+				v, v, part[v], c[0], c[1], c[2])
+		} else {
+			fmt.Fprintf(out, "  %d;\n", v)
+		}
+	}
+	g.EdgeIterator(func(u, v, weight int32) {
+		var attrs []string
+		if g.Adjwgt != nil {
+			attrs = append(attrs, fmt.Sprintf("label=\"%d\"", weight))
+		}
+		if part != nil && part[u] != part[v] {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(out, "  %d -- %d [%s];\n", u, v, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(out, "  %d -- %d;\n", u, v)
+		}
+	})
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// WritePLYPartition writes a point cloud as an ASCII PLY file in which every
+// vertex carries its coordinates and the RGB color of its partition, for
+// viewing partitions of point clouds and mesh nodes in MeshLab or CloudCompare.
+// coords and part must have the same length.
+func WritePLYPartition(w io.Writer, coords [][3]float64, part []int32) error {
+	if len(coords) != len(part) {
+		return fmt.Errorf("coords has %d points but part has %d entries", len(coords), len(part))
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "ply\nformat ascii 1.0\nelement vertex %d\n", len(coords))
+	fmt.Fprint(out, "property double x\nproperty double y\nproperty double z\n")
+	fmt.Fprint(out, "property uchar red\nproperty uchar green\nproperty uchar blue\nend_header\n")
+	for i, p := range coords {
+		c := partitionColor(part[i])
+		fmt.Fprintf(out, "%g %g %g %d %d %d\n", p[0], p[1], p[2], c[0], c[1], c[2])
+	}
+	return out.Flush()
+}
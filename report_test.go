@@ -0,0 +1,105 @@
+package metis
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionAndReport(t *testing.T) {
+	xadj, adjncy := createGridGraph(4, 4)
+	g := NewGraph(xadj, adjncy)
+	dir := filepath.Join(t.TempDir(), "out")
+
+	require.NoError(t, PartitionAndReport(g, 2, dir, nil, DefaultReportDOTLimit))
+
+	data, err := os.ReadFile(filepath.Join(dir, "partition.txt"))
+	require.NoError(t, err)
+	fields := strings.Fields(string(data))
+	require.Len(t, fields, 16)
+	part := make([]int32, len(fields))
+	for v, f := range fields {
+		p, err := strconv.Atoi(f)
+		require.NoError(t, err)
+		part[v] = int32(p)
+	}
+
+	stats, err := os.ReadFile(filepath.Join(dir, "partition.stats"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(stats)), "\n")
+	require.Len(t, lines, 10)
+	assert.Equal(t, "vertices 16", lines[0])
+	assert.Equal(t, "nparts 2", lines[2])
+	assert.Equal(t, fmt.Sprintf("edgecut %d", Metrics(g, part, 2).EdgeCut), lines[4])
+	assert.True(t, strings.HasPrefix(lines[8], "part 0 "))
+
+	dot, err := os.ReadFile(filepath.Join(dir, "partition.dot"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(dot), "graph G {"))
+	assert.Equal(t, g.NumEdges(), strings.Count(string(dot), " -- "))
+
+	// Without DOT output for graphs over the limit
+	dir = t.TempDir()
+	require.NoError(t, PartitionAndReport(g, 2, dir, nil, 0))
+	_, err = os.Stat(filepath.Join(dir, "partition.dot"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Error(t, PartitionAndReport(g, 0, t.TempDir(), nil, DefaultReportDOTLimit))
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := &Graph{
+		Xadj:   []int32{0, 1, 3, 4},
+		Adjncy: []int32{1, 0, 2, 1},
+		Adjwgt: []int32{5, 5, 2, 2},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDOT(&buf, g, []int32{0, 0, 1}))
+	assert.Contains(t, buf.String(), "  0 -- 1 [label=\"5\"];\n")
+	assert.Contains(t, buf.String(), "  1 -- 2 [label=\"2\", style=dashed];\n")
+	assert.Contains(t, buf.String(), "  2 [label=\"2:1\"")
+}
+
+func TestWritePartitionCSV(t *testing.T) {
+	// Path 0-1-2
+	g := NewGraph([]int32{0, 1, 3, 4}, []int32{1, 0, 2, 1})
+	part := []int32{0, 0, 1}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePartitionCSV(&buf, g, part, false))
+	assert.Equal(t, "vertex_id,partition\n0,0\n1,0\n2,1\n", buf.String())
+
+	buf.Reset()
+	g.Vwgt = []int32{4, 5, 6}
+	require.NoError(t, WritePartitionCSV(&buf, g, part, true))
+	assert.Equal(t, "vertex_id,partition,weight,degree\n0,0,4,1\n1,0,5,2\n2,1,6,1\n", buf.String())
+
+	assert.Error(t, WritePartitionCSV(&buf, g, part[:2], false))
+}
+
+func TestWritePLYPartition(t *testing.T) {
+	coords := [][3]float64{{0, 0, 0}, {1, 0.5, 0}, {0, 1, 2.25}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePLYPartition(&buf, coords, []int32{0, 1, 12}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 13)
+	assert.Equal(t, "ply", lines[0])
+	assert.Equal(t, "element vertex 3", lines[2])
+	assert.Equal(t, "end_header", lines[9])
+	assert.Equal(t, "0 0 0 141 211 199", lines[10])
+	assert.Equal(t, "1 0.5 0 255 255 179", lines[11])
+
+	// The palette wraps around after 12 partitions
+	assert.Equal(t, "0 1 2.25 141 211 199", lines[12])
+
+	assert.Error(t, WritePLYPartition(&buf, coords, []int32{0}))
+}
@@ -0,0 +1,137 @@
+package metis
+
+// ndLeafSize is the subdomain size below which SeparatorTree stops dissecting.
+// It matches the point at which METIS_NodeND switches from nested dissection to
+// minimum degree ordering.
+const ndLeafSize = 120
+
+// NDTree is a node of a nested dissection separator tree. Interior nodes hold
+// the vertex separator that splits their subdomain into the two children;
+// leaves hold the vertices of a subdomain that was not dissected further.
+// All vertex ids refer to the original graph.
+type NDTree struct {
+	Separator []int32   // Separator vertices (interior nodes only)
+	Vertices  []int32   // Subdomain vertices (leaves only)
+	Children  []*NDTree // The two halves split by Separator (nil for leaves)
+}
+
+// IsLeaf reports whether the node is an undissected subdomain
+func (t *NDTree) IsLeaf() bool {
+	return len(t.Children) == 0
+}
+
+// SeparatorTree computes the nested dissection separator hierarchy of a graph
+// by recursively applying ComputeVertexSeparator until subdomains fall below
+// the size at which METIS switches to minimum degree ordering.
+//
+// This is the structured counterpart of the sizes array returned by
+// METIS_NodeNDP: sizes lists the leaf subdomain sizes followed by the
+// separator sizes bottom-up, while the tree keeps the actual vertex sets and
+// how they nest. Every vertex of g appears exactly once, either in a separator
+// or in a leaf.
+func SeparatorTree(g *Graph, options []int32) (*NDTree, error) {
+	return RecursiveSeparators(g, ndLeafSize, options)
+}
+
+// RecursiveSeparators is SeparatorTree with a caller-chosen stopping size:
+// subdomains with fewer than minSize vertices become leaves, as do subdomains
+// without edges and those whose separator leaves one side empty. It gives
+// custom nested dissection orderings control over the recursion that NodeND
+// keeps to itself, for example to stop at the block size of a dense solver.
+func RecursiveSeparators(g *Graph, minSize int32, options []int32) (*NDTree, error) {
+	vertices := make([]int32, g.NumVertices())
+	for i := range vertices {
+		vertices[i] = int32(i)
+	}
+	return dissect(g, vertices, minSize, options)
+}
+
+// TopSeparatorSize returns the number of vertices in the top-level vertex
+// separator of g, the first cut nested dissection makes, as a concrete quality
+// measure for NodeND orderings: the fill of the factor grows with separator
+// sizes. It computes one separator with ComputeVertexSeparator, balancing the
+// vertex weights of g, so it reflects only the top level and not the full
+// recursion; see SeparatorTree for that. METIS randomizes its separators, so
+// the size may differ slightly from the one NodeND uses internally.
+func TopSeparatorSize(g *Graph, options []int32) (int32, error) {
+	_, part, err := ComputeVertexSeparator(g.Xadj, g.Adjncy, g.Vwgt, options)
+	if err != nil {
+		return 0, err
+	}
+
+	size := int32(0)
+	for _, p := range part {
+		if p == 2 {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// SeparatorBalance summarizes the part array returned by
+// ComputeVertexSeparator: the sizes of the two halves (part 0 and part 1), the
+// size of the separator (part 2) and the balance of the halves, the smaller
+// size divided by the larger. A balance near 1 means the separator splits the
+// graph evenly; near 0 it cuts off only a sliver, which makes it of little use
+// for nested dissection however small it is. The balance is 0 when both halves
+// are empty.
+func SeparatorBalance(part []int32) (sizeA, sizeB, sizeSep int, balance float64) {
+	for _, p := range part {
+		switch p {
+		case 0:
+			sizeA++
+		case 1:
+			sizeB++
+		case 2:
+			sizeSep++
+		}
+	}
+
+	small, large := sizeA, sizeB
+	if small > large {
+		small, large = large, small
+	}
+	if large > 0 {
+		balance = float64(small) / float64(large)
+	}
+	return sizeA, sizeB, sizeSep, balance
+}
+
+// dissect builds the separator tree for the subgraph induced by vertices
+func dissect(g *Graph, vertices []int32, minSize int32, options []int32) (*NDTree, error) {
+	sub := g.Subgraph(vertices)
+	if int32(len(vertices)) < minSize || len(sub.Adjncy) == 0 {
+		return &NDTree{Vertices: vertices}, nil
+	}
+
+	_, part, err := ComputeVertexSeparator(sub.Xadj, sub.Adjncy, sub.Vwgt, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var halves [2][]int32
+	var sep []int32
+	for i, p := range part {
+		if p == 2 {
+			sep = append(sep, vertices[i])
+		} else {
+			halves[p] = append(halves[p], vertices[i])
+		}
+	}
+
+	// A separator that leaves one side empty does not dissect anything
+	if len(halves[0]) == 0 || len(halves[1]) == 0 {
+		return &NDTree{Vertices: vertices}, nil
+	}
+
+	node := &NDTree{Separator: sep}
+	for _, half := range halves {
+		child, err := dissect(g, half, minSize, options)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,162 @@
+package metis
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeparatorTree(t *testing.T) {
+	nx, ny := 20, 20
+	xadj, adjncy := createGridGraph(nx, ny)
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	tree, err := SeparatorTree(g, opts)
+	require.NoError(t, err)
+	require.NotNil(t, tree)
+
+	// The root of a 400 vertex grid must be dissected
+	assert.False(t, tree.IsLeaf())
+	assert.Len(t, tree.Children, 2)
+
+	// Every vertex appears exactly once in a separator or a leaf
+	var seen []int32
+	var walk func(n *NDTree)
+	walk = func(n *NDTree) {
+		seen = append(seen, n.Separator...)
+		seen = append(seen, n.Vertices...)
+		if n.IsLeaf() {
+			assert.Empty(t, n.Separator)
+			assert.Less(t, len(n.Vertices), nx*ny)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+
+	sort.Slice(seen, func(i, j int) bool { return seen[i] < seen[j] })
+	require.Len(t, seen, nx*ny)
+	for i, v := range seen {
+		assert.Equal(t, int32(i), v)
+	}
+
+	t.Run("SmallGraphIsLeaf", func(t *testing.T) {
+		xadj, adjncy := createGridGraph(3, 3)
+		tree, err := SeparatorTree(NewGraph(xadj, adjncy), opts)
+		require.NoError(t, err)
+		assert.True(t, tree.IsLeaf())
+		assert.Len(t, tree.Vertices, 9)
+	})
+}
+
+// createGridGraph builds an nx by ny 4-connected grid in CSR format
+func createGridGraph(nx, ny int) ([]int32, []int32) {
+	xadj := make([]int32, nx*ny+1)
+	adjncy := []int32{}
+
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			v := j*nx + i
+			if j > 0 {
+				adjncy = append(adjncy, int32(v-nx))
+			}
+			if i > 0 {
+				adjncy = append(adjncy, int32(v-1))
+			}
+			if i < nx-1 {
+				adjncy = append(adjncy, int32(v+1))
+			}
+			if j < ny-1 {
+				adjncy = append(adjncy, int32(v+nx))
+			}
+			xadj[v+1] = int32(len(adjncy))
+		}
+	}
+
+	return xadj, adjncy
+}
+
+func TestTopSeparatorSize(t *testing.T) {
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	// A 20x20 grid splits across one row or column, give or take a few vertices
+	size, err := TopSeparatorSize(NewGraph(createGridGraph(20, 20)), opts)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, size, int32(20))
+	assert.LessOrEqual(t, size, int32(30))
+
+	_, err = TopSeparatorSize(NewGraph(nil, nil), opts)
+	assert.Error(t, err)
+}
+
+func TestSeparatorBalance(t *testing.T) {
+	a, b, sep, balance := SeparatorBalance([]int32{0, 0, 0, 2, 1, 1, 2, 0})
+	assert.Equal(t, 4, a)
+	assert.Equal(t, 2, b)
+	assert.Equal(t, 2, sep)
+	assert.Equal(t, 0.5, balance)
+
+	_, _, _, balance = SeparatorBalance([]int32{2, 2})
+	assert.Zero(t, balance)
+
+	// On an actual separator the counts add up to the graph size
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+	xadj, adjncy := createGridGraph(10, 10)
+	sepsize, part, err := ComputeVertexSeparator(xadj, adjncy, nil, opts)
+	require.NoError(t, err)
+	a, b, sep, balance = SeparatorBalance(part)
+	assert.Equal(t, 100, a+b+sep)
+	assert.Equal(t, int(sepsize), sep)
+	assert.Greater(t, balance, 0.5)
+}
+
+func TestRecursiveSeparators(t *testing.T) {
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+
+	xadj, adjncy := createGridGraph(16, 16)
+	tree, err := RecursiveSeparators(NewGraph(xadj, adjncy), 40, opts)
+	require.NoError(t, err)
+
+	// Far below the METIS leaf size, the 256 vertices are dissected down to
+	// leaves of fewer than 40 vertices
+	seen := make([]int, 256)
+	depth := 0
+	var walk func(n *NDTree, level int)
+	walk = func(n *NDTree, level int) {
+		if level > depth {
+			depth = level
+		}
+		for _, v := range n.Separator {
+			seen[v]++
+		}
+		for _, v := range n.Vertices {
+			seen[v]++
+		}
+		if n.IsLeaf() {
+			assert.Less(t, len(n.Vertices), 40)
+		}
+		for _, c := range n.Children {
+			walk(c, level+1)
+		}
+	}
+	walk(tree, 0)
+
+	for v, n := range seen {
+		assert.Equal(t, 1, n, "vertex %d", v)
+	}
+	assert.GreaterOrEqual(t, depth, 3)
+
+	// A minimum above the graph size leaves it undissected
+	tree, err = RecursiveSeparators(NewGraph(xadj, adjncy), 300, opts)
+	require.NoError(t, err)
+	assert.True(t, tree.IsLeaf())
+}
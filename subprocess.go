@@ -0,0 +1,100 @@
+package metis
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// subprocessEnv marks a process started by PartitionSubprocess
+const subprocessEnv = "GO_METIS_SUBPROCESS"
+
+// subprocessRequest is sent to the worker process on stdin
+type subprocessRequest struct {
+	Graph   *Graph
+	Nparts  int32
+	Options []int32
+}
+
+// subprocessResponse is returned by the worker process on stdout
+type subprocessResponse struct {
+	Part   []int32
+	Objval int32
+	Err    string
+}
+
+// RunSubprocessWorker turns the current process into a partitioning worker
+// when it was started by PartitionSubprocess: it serves the request read from
+// stdin and exits. In any other process it returns immediately. Programs using
+// PartitionSubprocess must call it at the very beginning of main (or TestMain).
+func RunSubprocessWorker() {
+	if os.Getenv(subprocessEnv) != "1" {
+		return
+	}
+
+	var req subprocessRequest
+	var resp subprocessResponse
+	if err := gob.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		resp.Err = fmt.Sprintf("decoding request: %v", err)
+	} else {
+		resp.Part, resp.Objval, err = PartitionGraph(req.Graph, req.Nparts, req.Options)
+		if err != nil {
+			resp.Err = err.Error()
+		}
+	}
+
+	if err := gob.NewEncoder(os.Stdout).Encode(&resp); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// PartitionSubprocess partitions g in a child process so that a METIS abort on
+// unexpected input terminates only the child. ValidateGraph catches the known
+// crash cases up front; this is the fallback for servers that must survive
+// anything a request can contain. The child is a re-execution of the current
+// binary, which must call RunSubprocessWorker at startup. OptionPType selects
+// recursive bisection or k-way partitioning.
+//
+// The graph is serialized to the child and back, so the overhead is only worth
+// paying when crash isolation matters.
+func PartitionSubprocess(ctx context.Context, g *Graph, nparts int32, options []int32) ([]int32, int32, error) {
+	if err := ValidateGraph(g.Xadj, g.Adjncy); err != nil {
+		return nil, 0, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, 0, fmt.Errorf("locating executable: %v", err)
+	}
+
+	var stdin, stdout, stderr bytes.Buffer
+	req := subprocessRequest{Graph: g, Nparts: nparts, Options: options}
+	if err := gob.NewEncoder(&stdin).Encode(&req); err != nil {
+		return nil, 0, fmt.Errorf("encoding request: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(os.Environ(), subprocessEnv+"=1")
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, 0, fmt.Errorf("partitioning subprocess failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var resp subprocessResponse
+	if err := gob.NewDecoder(&stdout).Decode(&resp); err != nil {
+		return nil, 0, fmt.Errorf("decoding subprocess response: %v", err)
+	}
+	if resp.Err != "" {
+		return nil, 0, errors.New(resp.Err)
+	}
+
+	return resp.Part, resp.Objval, nil
+}
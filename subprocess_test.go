@@ -0,0 +1,42 @@
+package metis
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	RunSubprocessWorker()
+	os.Exit(m.Run())
+}
+
+func TestPartitionSubprocess(t *testing.T) {
+	xadj, adjncy := createGridGraph(6, 6)
+	g := NewGraph(xadj, adjncy)
+
+	opts := make([]int32, NoOptions)
+	SetDefaultOptions(opts)
+	opts[OptionSeed] = 3
+
+	part, objval, err := PartitionSubprocess(context.Background(), g, 3, opts)
+	require.NoError(t, err)
+
+	// The child computes the same partition as an in-process call
+	local, localObjval, err := PartGraphKwayWeighted(xadj, adjncy, nil, nil, 3, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, local, part)
+	assert.Equal(t, localObjval, objval)
+
+	// METIS errors are passed back from the child
+	_, _, err = PartitionSubprocess(context.Background(), g, 0, opts)
+	assert.Error(t, err)
+
+	// Malformed graphs are rejected before starting a child
+	bad := NewGraph([]int32{0, 1, 2}, []int32{1, 5})
+	_, _, err = PartitionSubprocess(context.Background(), bad, 2, opts)
+	assert.ErrorContains(t, err, "adjncy index 1")
+}